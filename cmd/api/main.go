@@ -1,12 +1,17 @@
 package main
 
 import (
+	"context"
 	"log"
+	"strings"
+	"time"
 
+	mqtt "github.com/eclipse/paho.mqtt.golang"
 	"github.com/fahri/go-tije/internal/config"
 	"github.com/fahri/go-tije/internal/handler"
 	"github.com/fahri/go-tije/internal/repository"
 	"github.com/fahri/go-tije/internal/service"
+	mqttclient "github.com/fahri/go-tije/pkg/mqtt"
 	"github.com/fahri/go-tije/pkg/rabbitmq"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
@@ -31,9 +36,60 @@ func main() {
 	}
 	defer rmqPublisher.Close()
 	
+	geofenceRepo := repository.NewGeofenceRepository(db)
+	geofenceStateRepo := repository.NewGeofenceStateRepository(db)
+	groupRepo := repository.NewGroupRepository(db)
+	geofenceService := service.NewGeofenceService(geofenceRepo, geofenceStateRepo, groupRepo, &cfg.Geofence)
+	
+	// Locations posted to /ingest/locations are evaluated here, so the API
+	// keeps its own copy of the zones like the subscriber does.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := geofenceService.Refresh(ctx); err != nil {
+		log.Fatal("Failed to load geofences:", err)
+	}
+	go geofenceService.Watch(ctx, cfg.Geofence.RefreshInterval)
+	
 	vehicleRepo := repository.NewVehicleRepository(db)
-	vehicleService := service.NewVehicleService(vehicleRepo, rmqPublisher, &cfg.Geofence)
-	vehicleHandler := handler.NewVehicleHandler(vehicleService)
+	invalidRepo := repository.NewInvalidLocationRepository(db)
+	vehicleService := service.NewVehicleService(vehicleRepo, invalidRepo, rmqPublisher, geofenceService, &cfg.Ingest)
+	presenceService := service.NewPresenceService(repository.NewPresenceRepository(db), vehicleRepo)
+	vehicleHandler := handler.NewVehicleHandler(vehicleService, presenceService)
+	geofenceHandler := handler.NewGeofenceHandler(geofenceService)
+	groupService := service.NewGroupService(groupRepo)
+	groupHandler := handler.NewGroupHandler(groupService)
+	// Commands go out through the API's own MQTT connection; a separate
+	// client ID keeps it from disconnecting a subscriber with the same
+	// settings.
+	mqttConfig := cfg.MQTT
+	mqttConfig.ClientID += "-api"
+	mqttClient, err := mqttclient.NewClient(&mqttConfig)
+	if err != nil {
+		log.Fatal("Failed to connect to MQTT:", err)
+	}
+	defer mqttClient.Disconnect()
+	
+	commandService := service.NewCommandService(repository.NewCommandRepository(db), mqttClient, &cfg.Command)
+	commandHandler := handler.NewCommandHandler(commandService, cfg.Command.MaxTimeout)
+	go commandService.Watch(ctx)
+	
+	ackTopic := mqttclient.SharedTopic(cfg.MQTT.ShareGroup, "/fleet/vehicle/+/command/ack")
+	err = mqttClient.Subscribe(ackTopic, func(client mqtt.Client, msg mqtt.Message) {
+		ackCtx, ackCancel := context.WithTimeout(ctx, 10*time.Second)
+		defer ackCancel()
+		
+		vehicleID := vehicleIDFromTopic(msg.Topic())
+		if err := commandService.ProcessAck(ackCtx, vehicleID, msg.Payload()); err != nil {
+			log.Printf("Ignored command ack from %s: %v", vehicleID, err)
+		}
+	})
+	if err != nil {
+		log.Fatal("Failed to subscribe to command acks:", err)
+	}
+	
+	credentialService := service.NewCredentialService(repository.NewCredentialRepository(db))
+	credentialHandler := handler.NewCredentialHandler(credentialService)
+	ingestHandler := handler.NewIngestHandler(vehicleService, credentialService, cfg.Ingest.MaxBatch)
 	
 	app := fiber.New()
 	
@@ -43,9 +99,58 @@ func main() {
 	api := app.Group("/vehicles")
 	api.Get("/:vehicle_id/location", vehicleHandler.GetLatestLocation)
 	api.Get("/:vehicle_id/history", vehicleHandler.GetLocationHistory)
+	api.Get("/:vehicle_id/status", vehicleHandler.GetStatus)
+	api.Get("/:vehicle_id/commands", commandHandler.List)
+	api.Get("/:vehicle_id/commands/:id", commandHandler.Get)
+	
+	geofences := app.Group("/geofences")
+	geofences.Get("/", geofenceHandler.List)
+	geofences.Get("/export", geofenceHandler.Export)
+	geofences.Get("/:id", geofenceHandler.Get)
+	
+	groups := app.Group("/groups")
+	groups.Get("/", groupHandler.List)
+	groups.Get("/:id", groupHandler.Get)
+	
+	ingest := app.Group("/ingest")
+	ingest.Post("/locations", ingestHandler.Authenticate, ingestHandler.Locations)
+	
+	// Sending commands, changing zones, groups and credentials, and reading
+	// the rejection log is admin only; those routes are not served at all
+	// without an admin token. Group membership scopes partner credentials, so
+	// group changes are guarded like the credentials themselves.
+	if cfg.App.AdminToken != "" {
+		admin := handler.AdminAuth(cfg.App.AdminToken)
+		api.Post("/:vehicle_id/commands", admin, commandHandler.Send)
+		geofences.Post("/", admin, geofenceHandler.Create)
+		geofences.Post("/import", admin, geofenceHandler.Import)
+		geofences.Put("/:id", admin, geofenceHandler.Update)
+		geofences.Delete("/:id", admin, geofenceHandler.Delete)
+		groups.Post("/", admin, groupHandler.Create)
+		groups.Put("/:id", admin, groupHandler.Update)
+		groups.Delete("/:id", admin, groupHandler.Delete)
+		groups.Put("/:id/vehicles/:vehicle_id", admin, groupHandler.AddVehicle)
+		groups.Delete("/:id/vehicles/:vehicle_id", admin, groupHandler.RemoveVehicle)
+		ingest.Get("/rejections", admin, ingestHandler.Rejections)
+		ingest.Get("/rejections/recent", admin, ingestHandler.RecentRejections)
+		ingest.Get("/credentials", admin, credentialHandler.List)
+		ingest.Post("/credentials", admin, credentialHandler.Create)
+		ingest.Delete("/credentials/:id", admin, credentialHandler.Delete)
+	} else {
+		log.Println("ADMIN_TOKEN is not set; admin endpoints (vehicle commands, geofence and group changes, ingest credentials, rejections) are disabled")
+	}
 	
 	log.Printf("Server starting on port %s", cfg.App.Port)
 	if err := app.Listen(":" + cfg.App.Port); err != nil {
 		log.Fatal("Failed to start server:", err)
 	}
+}
+
+// vehicleIDFromTopic extracts {id} from /fleet/vehicle/{id}/...
+func vehicleIDFromTopic(topic string) string {
+	parts := strings.Split(topic, "/")
+	if len(parts) >= 4 {
+		return parts[3]
+	}
+	return ""
 }
\ No newline at end of file
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/fahri/go-tije/internal/config"
+	"github.com/fahri/go-tije/internal/domain"
+	"github.com/fahri/go-tije/internal/gateway"
+	"github.com/fahri/go-tije/internal/repository"
+	"github.com/fahri/go-tije/internal/service"
+	"github.com/fahri/go-tije/pkg/rabbitmq"
+)
+
+func main() {
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatal("Failed to load config:", err)
+	}
+
+	db, err := repository.NewDatabase(&cfg.DB)
+	if err != nil {
+		log.Fatal("Failed to connect to database:", err)
+	}
+	defer db.Close()
+
+	rmqPublisher, err := rabbitmq.NewPublisher(&cfg.RabbitMQ)
+	if err != nil {
+		log.Fatal("Failed to connect to RabbitMQ:", err)
+	}
+	defer rmqPublisher.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	geofenceRepo := repository.NewGeofenceRepository(db)
+	geofenceStateRepo := repository.NewGeofenceStateRepository(db)
+	groupRepo := repository.NewGroupRepository(db)
+	geofenceService := service.NewGeofenceService(geofenceRepo, geofenceStateRepo, groupRepo, &cfg.Geofence)
+	if err := geofenceService.Refresh(ctx); err != nil {
+		log.Fatal("Failed to load geofences:", err)
+	}
+	go geofenceService.Watch(ctx, cfg.Geofence.RefreshInterval)
+
+	vehicleRepo := repository.NewBatchVehicleRepository(db, &cfg.DB)
+	defer func() {
+		flushCtx, flushCancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer flushCancel()
+		if err := vehicleRepo.Close(flushCtx); err != nil {
+			log.Printf("Failed to flush buffered locations: %v", err)
+		}
+	}()
+	invalidRepo := repository.NewInvalidLocationRepository(db)
+	vehicleService := service.NewVehicleService(vehicleRepo, invalidRepo, rmqPublisher, geofenceService, &cfg.Ingest)
+
+	// Rejected and duplicate records are handled as far as the device is
+	// concerned; only processing failures make it resend.
+	handler := func(ctx context.Context, message *domain.LocationMessage) error {
+		var validationErr *domain.ValidationError
+		err := vehicleService.ProcessMessage(ctx, message)
+		switch {
+		case errors.As(err, &validationErr):
+			log.Printf("Rejected location from %s: %v", message.VehicleID, err)
+			return nil
+		case errors.Is(err, domain.ErrDuplicateLocation):
+			return nil
+		}
+		return err
+	}
+
+	server := gateway.NewServer(&cfg.Gateway, handler)
+	defer server.Close()
+
+	if addr := cfg.Gateway.TeltonikaAddr; addr != "off" {
+		if err := server.ListenTeltonika(addr); err != nil {
+			log.Fatal("Failed to listen for Teltonika devices:", err)
+		}
+		log.Printf("Listening for Teltonika devices on %s", addr)
+	}
+	if addr := cfg.Gateway.GT06Addr; addr != "off" {
+		if err := server.ListenGT06(addr); err != nil {
+			log.Fatal("Failed to listen for GT06 devices:", err)
+		}
+		log.Printf("Listening for GT06 devices on %s", addr)
+	}
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	<-sigChan
+
+	log.Println("Shutting down...")
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"encoding/json"
+	"flag"
 	"fmt"
 	"log"
 	"math"
@@ -11,8 +12,12 @@ import (
 	"syscall"
 	"time"
 
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/fahri/go-tije/internal/codec"
 	"github.com/fahri/go-tije/internal/config"
 	"github.com/fahri/go-tije/internal/domain"
+	"github.com/fahri/go-tije/internal/nmea"
+	"github.com/fahri/go-tije/pkg/geofence"
 	mqttclient "github.com/fahri/go-tije/pkg/mqtt"
 )
 
@@ -20,6 +25,9 @@ type VehicleSimulator struct {
 	vehicleID string
 	lat       float64
 	lon       float64
+	speed     float64
+	heading   float64
+	odometer  float64
 }
 
 func NewVehicleSimulator(vehicleID string, startLat, startLon float64) *VehicleSimulator {
@@ -39,28 +47,69 @@ func (v *VehicleSimulator) move() {
 	
 	v.lat = math.Max(-90, math.Min(90, v.lat))
 	v.lon = math.Max(-180, math.Min(180, v.lon))
+	
+	distance := geofence.CalculateDistance(
+		geofence.Point{Latitude: v.lat - deltaLat, Longitude: v.lon - deltaLon},
+		geofence.Point{Latitude: v.lat, Longitude: v.lon},
+	)
+	v.speed = distance / 2 * 3.6
+	v.heading = math.Mod(math.Atan2(deltaLon, deltaLat)*180/math.Pi+360, 360)
+	v.odometer += distance / 1000
 }
 
 func (v *VehicleSimulator) getLocation() domain.LocationMessage {
+	altitude := 8 + rand.Float64()*4
+	hdop := 0.8 + rand.Float64()
+	satellites := 7 + rand.Intn(6)
+	ignition := true
+	battery := 12.4 + rand.Float64()*0.4
+	
 	return domain.LocationMessage{
 		VehicleID: v.vehicleID,
 		Latitude:  v.lat,
 		Longitude: v.lon,
 		Timestamp: time.Now().Unix(),
+		Telemetry: domain.Telemetry{
+			Speed:          &v.speed,
+			Heading:        &v.heading,
+			Altitude:       &altitude,
+			HDOP:           &hdop,
+			Satellites:     &satellites,
+			Ignition:       &ignition,
+			Odometer:       &v.odometer,
+			BatteryVoltage: &battery,
+		},
 	}
 }
 
 func main() {
+	formatName := flag.String("format", "json", "payload format: json, protobuf, cbor or nmea")
+	replayFile := flag.String("replay", "", "replay the fixes of an NMEA log file instead of simulating vehicles")
+	replayVehicle := flag.String("vehicle", "B1234XYZ", "vehicle ID for -replay")
+	flag.Parse()
+	
+	format, err := codec.ParseFormat(*formatName)
+	if err != nil {
+		log.Fatal(err)
+	}
+	
 	cfg, err := config.Load()
 	if err != nil {
 		log.Fatal("Failed to load config:", err)
 	}
 	
-	mqttClient, err := mqttclient.NewClient(&cfg.MQTT)
-	if err != nil {
-		log.Fatal("Failed to connect to MQTT:", err)
+	if *replayFile != "" {
+		mqttClient, err := connectVehicle(cfg.MQTT, *replayVehicle)
+		if err != nil {
+			log.Fatal("Failed to connect to MQTT:", err)
+		}
+		defer mqttClient.Disconnect()
+		
+		if err := replay(mqttClient, format, *replayFile, *replayVehicle); err != nil {
+			log.Fatal("Failed to replay NMEA log:", err)
+		}
+		return
 	}
-	defer mqttClient.Disconnect()
 	
 	vehicles := []*VehicleSimulator{
 		NewVehicleSimulator("B1234XYZ", -6.2088, 106.8456),
@@ -68,13 +117,29 @@ func main() {
 		NewVehicleSimulator("B9012DEF", -6.2050, 106.8430),
 	}
 	
+	// Each simulated vehicle connects like a real tracker, so it reports its
+	// own online/offline status.
+	clients := make(map[string]*mqttclient.Client)
+	for _, vehicle := range vehicles {
+		mqttClient, err := connectVehicle(cfg.MQTT, vehicle.vehicleID)
+		if err != nil {
+			log.Fatal("Failed to connect to MQTT:", err)
+		}
+		defer mqttClient.Disconnect()
+		clients[vehicle.vehicleID] = mqttClient
+		
+		if err := ackCommands(mqttClient, vehicle.vehicleID); err != nil {
+			log.Fatal("Failed to subscribe to commands:", err)
+		}
+	}
+	
 	ticker := time.NewTicker(2 * time.Second)
 	defer ticker.Stop()
 	
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 	
-	log.Println("Starting MQTT publisher...")
+	log.Printf("Starting MQTT publisher (%s payloads)...", format)
 	
 	for {
 		select {
@@ -83,18 +148,18 @@ func main() {
 				vehicle.move()
 				location := vehicle.getLocation()
 				
-				data, err := json.Marshal(location)
+				data, err := codec.Encode(format, &location)
 				if err != nil {
 					log.Printf("Failed to marshal location: %v", err)
 					continue
 				}
 				
-				topic := fmt.Sprintf("/fleet/vehicle/%s/location", vehicle.vehicleID)
-				if err := mqttClient.Publish(topic, data); err != nil {
+				topic := fmt.Sprintf("/fleet/vehicle/%s/location", vehicle.vehicleID) + codec.TopicSuffix(format)
+				if err := clients[vehicle.vehicleID].Publish(topic, data); err != nil {
 					log.Printf("Failed to publish to %s: %v", topic, err)
 				} else {
-					log.Printf("Published location for %s: lat=%.4f, lon=%.4f",
-						vehicle.vehicleID, location.Latitude, location.Longitude)
+					log.Printf("Published location for %s: lat=%.4f, lon=%.4f, speed=%.1f km/h",
+						vehicle.vehicleID, location.Latitude, location.Longitude, vehicle.speed)
 				}
 			}
 			
@@ -103,4 +168,78 @@ func main() {
 			return
 		}
 	}
+}
+
+// connectVehicle connects as the tracker of vehicleID, with its own client ID
+// and status topic.
+func connectVehicle(cfg config.MQTTConfig, vehicleID string) (*mqttclient.Client, error) {
+	cfg.ClientID = cfg.ClientID + "-" + vehicleID
+	return mqttclient.NewClient(&cfg, mqttclient.WithStatus(fmt.Sprintf("/fleet/vehicle/%s/status", vehicleID)))
+}
+
+// ackCommands acknowledges every command sent to the vehicle, like a tracker
+// that carries them out.
+func ackCommands(mqttClient *mqttclient.Client, vehicleID string) error {
+	topic := fmt.Sprintf("/fleet/vehicle/%s/command", vehicleID)
+	return mqttClient.Subscribe(topic, func(client mqtt.Client, msg mqtt.Message) {
+		var command domain.CommandMessage
+		if err := json.Unmarshal(msg.Payload(), &command); err != nil {
+			log.Printf("Ignored invalid command for %s: %v", vehicleID, err)
+			return
+		}
+		log.Printf("Vehicle %s received command %s (%s)", vehicleID, command.Type, command.ID)
+		
+		ack, _ := json.Marshal(&domain.CommandAck{ID: command.ID, Status: "ok"})
+		// Waiting for a publish inside a message handler can stall the client.
+		go func() {
+			if err := mqttClient.Publish(topic+"/ack", ack); err != nil {
+				log.Printf("Failed to ack command %s: %v", command.ID, err)
+			}
+		}()
+	})
+}
+
+// replay publishes the fixes of an NMEA log every 2 seconds. Timestamps are
+// shifted so the first fix is stamped now, keeping the recorded spacing, so
+// old logs pass the ingest age check.
+func replay(mqttClient *mqttclient.Client, format codec.Format, path, vehicleID string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	
+	var fixes []*domain.LocationMessage
+	err = nmea.Decode(file, vehicleID, func(message *domain.LocationMessage) error {
+		fixes = append(fixes, message)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if len(fixes) == 0 {
+		return fmt.Errorf("no fixes in %s", path)
+	}
+	
+	log.Printf("Replaying %d fixes from %s as %s", len(fixes), path, vehicleID)
+	offset := time.Now().Unix() - fixes[0].Timestamp
+	topic := fmt.Sprintf("/fleet/vehicle/%s/location", vehicleID) + codec.TopicSuffix(format)
+	for i, fix := range fixes {
+		if i > 0 {
+			time.Sleep(2 * time.Second)
+		}
+		fix.Timestamp += offset
+		
+		data, err := codec.Encode(format, fix)
+		if err != nil {
+			return err
+		}
+		if err := mqttClient.Publish(topic, data); err != nil {
+			log.Printf("Failed to publish to %s: %v", topic, err)
+			continue
+		}
+		log.Printf("Replayed fix %d/%d: lat=%.4f, lon=%.4f", i+1, len(fixes), fix.Latitude, fix.Longitude)
+	}
+	
+	return nil
 }
\ No newline at end of file
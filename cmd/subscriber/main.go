@@ -2,18 +2,26 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
+	"time"
 
 	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/fahri/go-tije/internal/codec"
 	"github.com/fahri/go-tije/internal/config"
+	"github.com/fahri/go-tije/internal/domain"
 	"github.com/fahri/go-tije/internal/repository"
 	"github.com/fahri/go-tije/internal/service"
 	mqttclient "github.com/fahri/go-tije/pkg/mqtt"
 	"github.com/fahri/go-tije/pkg/rabbitmq"
+	"github.com/fahri/go-tije/pkg/workerpool"
 )
 
 func main() {
@@ -34,8 +42,32 @@ func main() {
 	}
 	defer rmqPublisher.Close()
 	
-	vehicleRepo := repository.NewVehicleRepository(db)
-	vehicleService := service.NewVehicleService(vehicleRepo, rmqPublisher, &cfg.Geofence)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	
+	geofenceRepo := repository.NewGeofenceRepository(db)
+	geofenceStateRepo := repository.NewGeofenceStateRepository(db)
+	groupRepo := repository.NewGroupRepository(db)
+	geofenceService := service.NewGeofenceService(geofenceRepo, geofenceStateRepo, groupRepo, &cfg.Geofence)
+	if err := geofenceService.Refresh(ctx); err != nil {
+		log.Fatal("Failed to load geofences:", err)
+	}
+	go geofenceService.Watch(ctx, cfg.Geofence.RefreshInterval)
+	
+	vehicleRepo := repository.NewBatchVehicleRepository(db, &cfg.DB)
+	defer func() {
+		flushCtx, flushCancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer flushCancel()
+		if err := vehicleRepo.Close(flushCtx); err != nil {
+			log.Printf("Failed to flush buffered locations: %v", err)
+		}
+	}()
+	invalidRepo := repository.NewInvalidLocationRepository(db)
+	vehicleService := service.NewVehicleService(vehicleRepo, invalidRepo, rmqPublisher, geofenceService, &cfg.Ingest)
+	presenceService := service.NewPresenceService(repository.NewPresenceRepository(db), vehicleRepo)
+	
+	pool := workerpool.New(cfg.Subscriber.Workers, cfg.Subscriber.QueueSize, cfg.Subscriber.MessageTimeout)
+	defer pool.Close()
 	
 	mqttClient, err := mqttclient.NewClient(&cfg.MQTT)
 	if err != nil {
@@ -43,26 +75,118 @@ func main() {
 	}
 	defer mqttClient.Disconnect()
 	
-	topic := "/fleet/vehicle/+/location"
+	// JSON arrives on .../location, compact payloads on .../location/pb and
+	// .../location/cbor, raw NMEA sentences on .../location/nmea.
+	topics := []string{"/fleet/vehicle/+/location", "/fleet/vehicle/+/location/+"}
+	for i, topic := range topics {
+		topics[i] = mqttclient.SharedTopic(cfg.MQTT.ShareGroup, topic)
+	}
 	handler := func(client mqtt.Client, msg mqtt.Message) {
-		log.Printf("Received location data from topic %s", msg.Topic())
+		msgTopic, payload := msg.Topic(), msg.Payload()
 		
-		if err := vehicleService.ProcessLocation(context.Background(), msg.Payload()); err != nil {
-			log.Printf("Failed to process location: %v", err)
-		} else {
-			log.Printf("Location processed successfully")
+		// Messages of one vehicle share a worker so they are processed in order;
+		// Submit blocks while that worker is backed up.
+		err := pool.Submit(ctx, vehicleIDFromTopic(msgTopic), func(ctx context.Context) {
+			processLocation(ctx, vehicleService, msgTopic, payload)
+		})
+		if err != nil {
+			log.Printf("Dropped location from topic %s: %v", msgTopic, err)
 		}
 	}
 	
-	if err := mqttClient.Subscribe(topic, handler); err != nil {
+	for _, topic := range topics {
+		if err := mqttClient.Subscribe(topic, handler); err != nil {
+			log.Fatal("Failed to subscribe to topic:", err)
+		}
+	}
+	
+	// Trackers report "online" on .../status and leave "offline" as their
+	// Last Will.
+	statusTopic := mqttclient.SharedTopic(cfg.MQTT.ShareGroup, "/fleet/vehicle/+/status")
+	statusHandler := func(client mqtt.Client, msg mqtt.Message) {
+		msgTopic, payload, retained := msg.Topic(), msg.Payload(), msg.Retained()
+		vehicleID := vehicleIDFromTopic(msgTopic)
+		
+		err := pool.Submit(ctx, vehicleID, func(ctx context.Context) {
+			if err := presenceService.ProcessStatus(ctx, vehicleID, payload, retained); err != nil {
+				log.Printf("Failed to process status from topic %s: %v", msgTopic, err)
+				return
+			}
+			log.Printf("Vehicle %s reported %s", vehicleID, payload)
+		})
+		if err != nil {
+			log.Printf("Dropped status from topic %s: %v", msgTopic, err)
+		}
+	}
+	if err := mqttClient.Subscribe(statusTopic, statusHandler); err != nil {
 		log.Fatal("Failed to subscribe to topic:", err)
 	}
+	topics = append(topics, statusTopic)
 	
-	fmt.Printf("MQTT Subscriber started. Listening to topic: %s\n", topic)
+	if cfg.Subscriber.HealthAddr != "off" {
+		go serveHealth(cfg.Subscriber.HealthAddr, mqttClient)
+	}
+	
+	fmt.Printf("MQTT Subscriber started with %d workers. Listening to topics: %s\n", cfg.Subscriber.Workers, strings.Join(topics, ", "))
 	
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 	<-sigChan
 	
 	log.Println("Shutting down...")
+}
+
+func processLocation(ctx context.Context, vehicleService service.VehicleService, topic string, payload []byte) {
+	log.Printf("Received location data from topic %s", topic)
+	
+	var err error
+	if format := codec.FormatFromTopic(topic); format == codec.FormatNMEA {
+		err = vehicleService.ProcessNMEA(ctx, vehicleIDFromTopic(topic), payload)
+	} else {
+		err = vehicleService.ProcessPayload(ctx, format, payload)
+	}
+	
+	var validationErr *domain.ValidationError
+	if errors.As(err, &validationErr) {
+		log.Printf("Rejected location from topic %s: %v", topic, err)
+	} else if errors.Is(err, domain.ErrDuplicateLocation) {
+		log.Printf("Skipped duplicate location from topic %s", topic)
+	} else if err != nil {
+		log.Printf("Failed to process location: %v", err)
+	} else {
+		log.Printf("Location processed successfully")
+	}
+}
+
+// serveHealth answers GET /healthz with the MQTT connection state, with 503
+// while the broker connection is down.
+func serveHealth(addr string, mqttClient *mqttclient.Client) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		state := mqttClient.State()
+		status := "ok"
+		w.Header().Set("Content-Type", "application/json")
+		if !state.Connected {
+			status = "unavailable"
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"status": status,
+			"mqtt":   state,
+		})
+	})
+	
+	log.Printf("Health check listening on %s", addr)
+	if err := http.ListenAndServe(addr, mux); err != nil {
+		log.Printf("Health check server stopped: %v", err)
+	}
+}
+
+// vehicleIDFromTopic extracts {id} from /fleet/vehicle/{id}/location.
+func vehicleIDFromTopic(topic string) string {
+	parts := strings.Split(topic, "/")
+	if len(parts) >= 4 && parts[3] != "" {
+		return parts[3]
+	}
+	return topic
 }
\ No newline at end of file
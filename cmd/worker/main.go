@@ -116,9 +116,20 @@ func processMessage(msg amqp.Delivery) {
 
 	log.Printf("=== GEOFENCE ALERT ===")
 	log.Printf("Vehicle ID: %s", event.VehicleID)
+	log.Printf("Geofence: %s (%s)", event.GeofenceName, event.GeofenceID)
+	if event.GroupID != "" {
+		log.Printf("Group: %s (%s)", event.GroupName, event.GroupID)
+	}
 	log.Printf("Event Type: %s", event.Event)
 	log.Printf("Location: %.4f, %.4f", event.Location.Latitude, event.Location.Longitude)
 	log.Printf("Timestamp: %s", time.Unix(event.Timestamp, 0).Format("2006-01-02 15:04:05"))
+	if event.Duration > 0 {
+		label := "Time Inside"
+		if event.Event == domain.GeofenceEventRouteDeviation {
+			label = "Time Off Route"
+		}
+		log.Printf("%s: %s", label, time.Duration(event.Duration)*time.Second)
+	}
 	log.Printf("=====================")
 
 	
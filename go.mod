@@ -3,12 +3,14 @@ module github.com/fahri/go-tije
 go 1.21
 
 require (
-	github.com/gofiber/fiber/v2 v2.52.0
 	github.com/eclipse/paho.mqtt.golang v1.4.3
+	github.com/fxamacker/cbor/v2 v2.5.0
+	github.com/gofiber/fiber/v2 v2.52.0
+	github.com/google/uuid v1.5.0
 	github.com/jackc/pgx/v5 v5.5.1
-	github.com/streadway/amqp v1.1.0
 	github.com/joho/godotenv v1.5.1
-	github.com/google/uuid v1.5.0
+	github.com/streadway/amqp v1.1.0
+	google.golang.org/protobuf v1.33.0
 )
 
 require (
@@ -25,9 +27,10 @@ require (
 	github.com/valyala/bytebufferpool v1.0.0 // indirect
 	github.com/valyala/fasthttp v1.51.0 // indirect
 	github.com/valyala/tcplisten v1.0.0 // indirect
+	github.com/x448/float16 v0.8.4 // indirect
 	golang.org/x/crypto v0.17.0 // indirect
-	golang.org/x/net v0.10.0 // indirect
+	golang.org/x/net v0.17.0 // indirect
 	golang.org/x/sync v0.1.0 // indirect
 	golang.org/x/sys v0.15.0 // indirect
 	golang.org/x/text v0.14.0 // indirect
-)
\ No newline at end of file
+)
@@ -0,0 +1,12 @@
+package codec
+
+import (
+	"github.com/fxamacker/cbor/v2"
+)
+
+// CBOR maps use the JSON field names; the struct tags are shared. Floats are
+// encoded in the shortest width that keeps their value.
+var (
+	cborEncMode, _ = cbor.EncOptions{ShortestFloat: cbor.ShortestFloat16}.EncMode()
+	cborDecMode, _ = cbor.DecOptions{}.DecMode()
+)
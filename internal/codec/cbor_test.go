@@ -0,0 +1,59 @@
+package codec
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/fxamacker/cbor/v2"
+)
+
+func TestCBORUsesJSONFieldNames(t *testing.T) {
+	// A map as another CBOR library would send it.
+	data, err := cbor.Marshal(map[string]any{
+		"vehicle_id":      "B1234XYZ",
+		"latitude":        -6.2088,
+		"longitude":       106.8456,
+		"timestamp":       1715000000,
+		"message_id":      "msg-42",
+		"speed":           42.5,
+		"heading":         270.25,
+		"altitude":        12,
+		"hdop":            0.75,
+		"accuracy":        3.5,
+		"satellites":      9,
+		"fix_quality":     1,
+		"ignition":        false,
+		"odometer":        12345.678,
+		"battery_voltage": 12.5,
+	})
+	if err != nil {
+		t.Fatalf("cbor.Marshal: %v", err)
+	}
+
+	got, err := Decode(FormatCBOR, data)
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if want := testMessages()["full telemetry"]; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestCBOROmitsMissingTelemetry(t *testing.T) {
+	data, err := Encode(FormatCBOR, testMessages()["nil telemetry"])
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+
+	var fields map[string]any
+	if err := cbor.Unmarshal(data, &fields); err != nil {
+		t.Fatalf("cbor.Unmarshal: %v", err)
+	}
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	if len(fields) != 4 {
+		t.Errorf("encoded keys %v, want only vehicle_id, latitude, longitude and timestamp", keys)
+	}
+}
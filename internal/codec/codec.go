@@ -0,0 +1,99 @@
+// Package codec decodes and encodes location messages in the payload formats
+// accepted on the MQTT topics: JSON, protobuf, CBOR and NMEA 0183.
+package codec
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/fahri/go-tije/internal/domain"
+	"github.com/fahri/go-tije/internal/nmea"
+)
+
+type Format string
+
+const (
+	FormatJSON     Format = "json"
+	FormatProtobuf Format = "protobuf"
+	FormatCBOR     Format = "cbor"
+	FormatNMEA     Format = "nmea"
+)
+
+// ParseFormat accepts a format name as used by flags and query strings.
+func ParseFormat(name string) (Format, error) {
+	switch strings.ToLower(name) {
+	case "", "json":
+		return FormatJSON, nil
+	case "protobuf", "proto", "pb":
+		return FormatProtobuf, nil
+	case "cbor":
+		return FormatCBOR, nil
+	case "nmea":
+		return FormatNMEA, nil
+	}
+	return "", fmt.Errorf("unsupported format: %s", name)
+}
+
+// FormatFromTopic picks the format from the topic suffix:
+// /fleet/vehicle/{id}/location is JSON, .../location/pb protobuf,
+// .../location/cbor CBOR and .../location/nmea NMEA sentences.
+func FormatFromTopic(topic string) Format {
+	switch topic[strings.LastIndex(topic, "/")+1:] {
+	case "pb", "proto", "protobuf":
+		return FormatProtobuf
+	case "cbor":
+		return FormatCBOR
+	case "nmea":
+		return FormatNMEA
+	}
+	return FormatJSON
+}
+
+// TopicSuffix is appended to the location topic when publishing in format.
+func TopicSuffix(format Format) string {
+	switch format {
+	case FormatProtobuf:
+		return "/pb"
+	case FormatCBOR:
+		return "/cbor"
+	case FormatNMEA:
+		return "/nmea"
+	}
+	return ""
+}
+
+// Decode decodes a single location. NMEA payloads carry no vehicle ID and
+// may hold several fixes, so they are decoded with nmea.Parse instead.
+func Decode(format Format, data []byte) (*domain.LocationMessage, error) {
+	var message domain.LocationMessage
+	var err error
+	switch format {
+	case FormatNMEA:
+		return nil, fmt.Errorf("NMEA payloads must be decoded with the vehicle ID")
+	case FormatProtobuf:
+		err = unmarshalProtobuf(data, &message)
+	case FormatCBOR:
+		err = cborDecMode.Unmarshal(data, &message)
+	default:
+		err = json.Unmarshal(data, &message)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &message, nil
+}
+
+func Encode(format Format, message *domain.LocationMessage) ([]byte, error) {
+	switch format {
+	case FormatProtobuf:
+		return marshalProtobuf(message), nil
+	case FormatCBOR:
+		return cborEncMode.Marshal(message)
+	case FormatNMEA:
+		return nmea.Encode(message), nil
+	default:
+		return json.Marshal(message)
+	}
+}
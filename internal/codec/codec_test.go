@@ -0,0 +1,65 @@
+package codec
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/fahri/go-tije/internal/domain"
+)
+
+func float(v float64) *float64 { return &v }
+func integer(v int) *int       { return &v }
+func boolean(v bool) *bool     { return &v }
+
+// testMessages returns a location without telemetry and one with every
+// reading set. Readings sent as protobuf floats are float32-exact so they
+// survive the round trip unchanged.
+func testMessages() map[string]*domain.LocationMessage {
+	return map[string]*domain.LocationMessage{
+		"nil telemetry": {
+			VehicleID: "B1234XYZ",
+			Latitude:  -6.2088,
+			Longitude: 106.8456,
+			Timestamp: 1715000000,
+		},
+		"full telemetry": {
+			VehicleID: "B1234XYZ",
+			Latitude:  -6.2088,
+			Longitude: 106.8456,
+			Timestamp: 1715000000,
+			MessageID: "msg-42",
+			Telemetry: domain.Telemetry{
+				Speed:          float(42.5),
+				Heading:        float(270.25),
+				Altitude:       float(12),
+				HDOP:           float(0.75),
+				Accuracy:       float(3.5),
+				Satellites:     integer(9),
+				FixQuality:     integer(1),
+				Ignition:       boolean(false),
+				Odometer:       float(12345.678),
+				BatteryVoltage: float(12.5),
+			},
+		},
+	}
+}
+
+func TestEncodeDecodeRoundTrip(t *testing.T) {
+	for _, format := range []Format{FormatJSON, FormatProtobuf, FormatCBOR} {
+		for name, want := range testMessages() {
+			t.Run(string(format)+"/"+name, func(t *testing.T) {
+				data, err := Encode(format, want)
+				if err != nil {
+					t.Fatalf("Encode: %v", err)
+				}
+				got, err := Decode(format, data)
+				if err != nil {
+					t.Fatalf("Decode: %v", err)
+				}
+				if !reflect.DeepEqual(got, want) {
+					t.Errorf("round trip changed the message:\n got %+v\nwant %+v", got, want)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package codec
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/fahri/go-tije/internal/domain"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Field numbers of fleet.v1.Location, see location.proto.
+const (
+	fieldVehicleID      protowire.Number = 1
+	fieldLatitude       protowire.Number = 2
+	fieldLongitude      protowire.Number = 3
+	fieldTimestamp      protowire.Number = 4
+	fieldMessageID      protowire.Number = 5
+	fieldSpeed          protowire.Number = 6
+	fieldHeading        protowire.Number = 7
+	fieldAltitude       protowire.Number = 8
+	fieldHDOP           protowire.Number = 9
+	fieldAccuracy       protowire.Number = 10
+	fieldSatellites     protowire.Number = 11
+	fieldIgnition       protowire.Number = 12
+	fieldOdometer       protowire.Number = 13
+	fieldBatteryVoltage protowire.Number = 14
+	fieldFixQuality     protowire.Number = 15
+)
+
+func marshalProtobuf(m *domain.LocationMessage) []byte {
+	var b []byte
+	if m.VehicleID != "" {
+		b = protowire.AppendTag(b, fieldVehicleID, protowire.BytesType)
+		b = protowire.AppendString(b, m.VehicleID)
+	}
+	if m.Latitude != 0 {
+		b = appendDouble(b, fieldLatitude, m.Latitude)
+	}
+	if m.Longitude != 0 {
+		b = appendDouble(b, fieldLongitude, m.Longitude)
+	}
+	if m.Timestamp != 0 {
+		b = protowire.AppendTag(b, fieldTimestamp, protowire.VarintType)
+		b = protowire.AppendVarint(b, uint64(m.Timestamp))
+	}
+	if m.MessageID != "" {
+		b = protowire.AppendTag(b, fieldMessageID, protowire.BytesType)
+		b = protowire.AppendString(b, m.MessageID)
+	}
+
+	t := &m.Telemetry
+	b = appendFloat(b, fieldSpeed, t.Speed)
+	b = appendFloat(b, fieldHeading, t.Heading)
+	b = appendFloat(b, fieldAltitude, t.Altitude)
+	b = appendFloat(b, fieldHDOP, t.HDOP)
+	b = appendFloat(b, fieldAccuracy, t.Accuracy)
+	if t.Satellites != nil {
+		b = protowire.AppendTag(b, fieldSatellites, protowire.VarintType)
+		b = protowire.AppendVarint(b, uint64(uint32(*t.Satellites)))
+	}
+	if t.Ignition != nil {
+		b = protowire.AppendTag(b, fieldIgnition, protowire.VarintType)
+		b = protowire.AppendVarint(b, protowire.EncodeBool(*t.Ignition))
+	}
+	if t.Odometer != nil {
+		b = appendDouble(b, fieldOdometer, *t.Odometer)
+	}
+	b = appendFloat(b, fieldBatteryVoltage, t.BatteryVoltage)
+	if t.FixQuality != nil {
+		b = protowire.AppendTag(b, fieldFixQuality, protowire.VarintType)
+		b = protowire.AppendVarint(b, uint64(uint32(*t.FixQuality)))
+	}
+
+	return b
+}
+
+func appendDouble(b []byte, num protowire.Number, v float64) []byte {
+	b = protowire.AppendTag(b, num, protowire.Fixed64Type)
+	return protowire.AppendFixed64(b, math.Float64bits(v))
+}
+
+func appendFloat(b []byte, num protowire.Number, v *float64) []byte {
+	if v == nil {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.Fixed32Type)
+	return protowire.AppendFixed32(b, math.Float32bits(float32(*v)))
+}
+
+func unmarshalProtobuf(b []byte, m *domain.LocationMessage) error {
+	t := &m.Telemetry
+	floats := map[protowire.Number]**float64{
+		fieldSpeed:          &t.Speed,
+		fieldHeading:        &t.Heading,
+		fieldAltitude:       &t.Altitude,
+		fieldHDOP:           &t.HDOP,
+		fieldAccuracy:       &t.Accuracy,
+		fieldBatteryVoltage: &t.BatteryVoltage,
+	}
+
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return fieldError(0, n)
+		}
+		b = b[n:]
+
+		// Fields with an unexpected wire type are skipped like unknown ones.
+		switch {
+		case typ == protowire.BytesType && (num == fieldVehicleID || num == fieldMessageID):
+			v, n := protowire.ConsumeString(b)
+			if n < 0 {
+				return fieldError(num, n)
+			}
+			b = b[n:]
+			if num == fieldVehicleID {
+				m.VehicleID = v
+			} else {
+				m.MessageID = v
+			}
+		case typ == protowire.Fixed64Type && (num == fieldLatitude || num == fieldLongitude || num == fieldOdometer):
+			v, n := protowire.ConsumeFixed64(b)
+			if n < 0 {
+				return fieldError(num, n)
+			}
+			b = b[n:]
+			switch f := math.Float64frombits(v); num {
+			case fieldLatitude:
+				m.Latitude = f
+			case fieldLongitude:
+				m.Longitude = f
+			default:
+				t.Odometer = &f
+			}
+		case typ == protowire.VarintType && (num == fieldTimestamp || num == fieldSatellites || num == fieldIgnition || num == fieldFixQuality):
+			v, n := protowire.ConsumeVarint(b)
+			if n < 0 {
+				return fieldError(num, n)
+			}
+			b = b[n:]
+			switch num {
+			case fieldTimestamp:
+				m.Timestamp = int64(v)
+			case fieldSatellites:
+				satellites := int(uint32(v))
+				t.Satellites = &satellites
+			case fieldFixQuality:
+				quality := int(uint32(v))
+				t.FixQuality = &quality
+			default:
+				ignition := protowire.DecodeBool(v)
+				t.Ignition = &ignition
+			}
+		case typ == protowire.Fixed32Type && floats[num] != nil:
+			v, n := protowire.ConsumeFixed32(b)
+			if n < 0 {
+				return fieldError(num, n)
+			}
+			b = b[n:]
+			f := float64(math.Float32frombits(v))
+			*floats[num] = &f
+		default:
+			n := protowire.ConsumeFieldValue(num, typ, b)
+			if n < 0 {
+				return fieldError(num, n)
+			}
+			b = b[n:]
+		}
+	}
+
+	return nil
+}
+
+func fieldError(num protowire.Number, n int) error {
+	return fmt.Errorf("invalid protobuf field %d: %v", num, protowire.ParseError(n))
+}
@@ -0,0 +1,171 @@
+package codec
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/fahri/go-tije/internal/domain"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// referenceLocation builds fleet.v1.Location from the same definition as
+// location.proto, so the protobuf runtime can act as a reference encoder and
+// decoder for the hand-written one.
+func referenceLocation(t *testing.T) protoreflect.MessageDescriptor {
+	t.Helper()
+
+	message := &descriptorpb.DescriptorProto{Name: proto.String("Location")}
+	add := func(name string, number int32, typ descriptorpb.FieldDescriptorProto_Type, optional bool) {
+		field := &descriptorpb.FieldDescriptorProto{
+			Name:     proto.String(name),
+			JsonName: proto.String(name),
+			Number:   proto.Int32(number),
+			Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+			Type:     typ.Enum(),
+		}
+		if optional {
+			field.Proto3Optional = proto.Bool(true)
+			field.OneofIndex = proto.Int32(int32(len(message.OneofDecl)))
+			message.OneofDecl = append(message.OneofDecl, &descriptorpb.OneofDescriptorProto{Name: proto.String("_" + name)})
+		}
+		message.Field = append(message.Field, field)
+	}
+
+	add("vehicle_id", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING, false)
+	add("latitude", 2, descriptorpb.FieldDescriptorProto_TYPE_DOUBLE, false)
+	add("longitude", 3, descriptorpb.FieldDescriptorProto_TYPE_DOUBLE, false)
+	add("timestamp", 4, descriptorpb.FieldDescriptorProto_TYPE_INT64, false)
+	add("message_id", 5, descriptorpb.FieldDescriptorProto_TYPE_STRING, false)
+	add("speed", 6, descriptorpb.FieldDescriptorProto_TYPE_FLOAT, true)
+	add("heading", 7, descriptorpb.FieldDescriptorProto_TYPE_FLOAT, true)
+	add("altitude", 8, descriptorpb.FieldDescriptorProto_TYPE_FLOAT, true)
+	add("hdop", 9, descriptorpb.FieldDescriptorProto_TYPE_FLOAT, true)
+	add("accuracy", 10, descriptorpb.FieldDescriptorProto_TYPE_FLOAT, true)
+	add("satellites", 11, descriptorpb.FieldDescriptorProto_TYPE_UINT32, true)
+	add("ignition", 12, descriptorpb.FieldDescriptorProto_TYPE_BOOL, true)
+	add("odometer", 13, descriptorpb.FieldDescriptorProto_TYPE_DOUBLE, true)
+	add("battery_voltage", 14, descriptorpb.FieldDescriptorProto_TYPE_FLOAT, true)
+	add("fix_quality", 15, descriptorpb.FieldDescriptorProto_TYPE_UINT32, true)
+
+	file, err := protodesc.NewFile(&descriptorpb.FileDescriptorProto{
+		Name:        proto.String("location.proto"),
+		Package:     proto.String("fleet.v1"),
+		Syntax:      proto.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{message},
+	}, nil)
+	if err != nil {
+		t.Fatalf("building descriptor: %v", err)
+	}
+	return file.Messages().ByName("Location")
+}
+
+// referenceMessage sets the dynamic message fields from m.
+func referenceMessage(desc protoreflect.MessageDescriptor, m *domain.LocationMessage) *dynamicpb.Message {
+	msg := dynamicpb.NewMessage(desc)
+	set := func(name string, v protoreflect.Value) {
+		msg.Set(desc.Fields().ByName(protoreflect.Name(name)), v)
+	}
+	setFloat := func(name string, v *float64) {
+		if v != nil {
+			set(name, protoreflect.ValueOfFloat32(float32(*v)))
+		}
+	}
+
+	set("vehicle_id", protoreflect.ValueOfString(m.VehicleID))
+	set("latitude", protoreflect.ValueOfFloat64(m.Latitude))
+	set("longitude", protoreflect.ValueOfFloat64(m.Longitude))
+	set("timestamp", protoreflect.ValueOfInt64(m.Timestamp))
+	set("message_id", protoreflect.ValueOfString(m.MessageID))
+
+	tm := m.Telemetry
+	setFloat("speed", tm.Speed)
+	setFloat("heading", tm.Heading)
+	setFloat("altitude", tm.Altitude)
+	setFloat("hdop", tm.HDOP)
+	setFloat("accuracy", tm.Accuracy)
+	setFloat("battery_voltage", tm.BatteryVoltage)
+	if tm.Satellites != nil {
+		set("satellites", protoreflect.ValueOfUint32(uint32(*tm.Satellites)))
+	}
+	if tm.FixQuality != nil {
+		set("fix_quality", protoreflect.ValueOfUint32(uint32(*tm.FixQuality)))
+	}
+	if tm.Ignition != nil {
+		set("ignition", protoreflect.ValueOfBool(*tm.Ignition))
+	}
+	if tm.Odometer != nil {
+		set("odometer", protoreflect.ValueOfFloat64(*tm.Odometer))
+	}
+	return msg
+}
+
+func TestProtobufDecodesReferenceMessage(t *testing.T) {
+	desc := referenceLocation(t)
+	for name, want := range testMessages() {
+		t.Run(name, func(t *testing.T) {
+			data, err := proto.Marshal(referenceMessage(desc, want))
+			if err != nil {
+				t.Fatalf("proto.Marshal: %v", err)
+			}
+			got, err := Decode(FormatProtobuf, data)
+			if err != nil {
+				t.Fatalf("Decode: %v", err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("decoded reference message:\n got %+v\nwant %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestProtobufEncodesReferenceMessage(t *testing.T) {
+	desc := referenceLocation(t)
+	for name, m := range testMessages() {
+		t.Run(name, func(t *testing.T) {
+			data, err := Encode(FormatProtobuf, m)
+			if err != nil {
+				t.Fatalf("Encode: %v", err)
+			}
+
+			got := dynamicpb.NewMessage(desc)
+			if err := proto.Unmarshal(data, got); err != nil {
+				t.Fatalf("proto.Unmarshal: %v", err)
+			}
+			// A wrong field number or wire type leaves the field unknown.
+			if unknown := got.GetUnknown(); len(unknown) > 0 {
+				t.Errorf("reference decoder left unknown fields: %x", unknown)
+			}
+			if want := referenceMessage(desc, m); !proto.Equal(got, want) {
+				t.Errorf("reference decoder read %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestProtobufSkipsUnknownFields(t *testing.T) {
+	want := testMessages()["nil telemetry"]
+	data, _ := Encode(FormatProtobuf, want)
+	// Field 20 as a string, and field 6 (speed) with the wrong wire type.
+	data = append(data, 0xa2, 0x01, 0x02, 'h', 'i', 0x30, 0x05)
+
+	got, err := Decode(FormatProtobuf, data)
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestProtobufRejectsTruncatedMessage(t *testing.T) {
+	data, _ := Encode(FormatProtobuf, testMessages()["full telemetry"])
+	for _, n := range []int{1, 5, len(data) - 1} {
+		if _, err := Decode(FormatProtobuf, data[:n]); err == nil {
+			t.Errorf("Decode of the first %d of %d bytes succeeded", n, len(data))
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	CommandTypeSetInterval     = "set_interval"
+	CommandTypeRequestPosition = "request_position"
+	CommandTypeBuzzer          = "buzzer"
+)
+
+const (
+	CommandStatusPending  = "pending"
+	CommandStatusAcked    = "acked"
+	CommandStatusFailed   = "failed"
+	CommandStatusTimedOut = "timed_out"
+)
+
+// VehicleCommand is a command sent to a tracker on
+// /fleet/vehicle/{id}/command. It stays pending until the tracker acks it or
+// ExpiresAt passes.
+type VehicleCommand struct {
+	ID        string        `json:"id" db:"id"`
+	VehicleID string        `json:"vehicle_id" db:"vehicle_id"`
+	Type      string        `json:"type" db:"type"`
+	Params    CommandParams `json:"params" db:"params"`
+	Status    string        `json:"status" db:"status"`
+	Error     string        `json:"error,omitempty" db:"error"`
+	ExpiresAt time.Time     `json:"expires_at" db:"expires_at"`
+	AckedAt   *time.Time    `json:"acked_at,omitempty" db:"acked_at"`
+	CreatedAt time.Time     `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time     `json:"updated_at" db:"updated_at"`
+}
+
+// CommandParams holds the arguments of every command type; each type uses
+// its own fields.
+type CommandParams struct {
+	// IntervalSeconds is the new reporting interval of set_interval.
+	IntervalSeconds int `json:"interval_seconds,omitempty"`
+	// DurationSeconds is how long buzzer sounds, 5 when omitted.
+	DurationSeconds int `json:"duration_seconds,omitempty"`
+}
+
+func (c *VehicleCommand) Validate() error {
+	if c.VehicleID == "" || len(c.VehicleID) > 50 {
+		return fmt.Errorf("vehicle_id must be 1 to 50 characters")
+	}
+
+	switch c.Type {
+	case CommandTypeSetInterval:
+		if c.Params.IntervalSeconds < 1 || c.Params.IntervalSeconds > 86400 {
+			return fmt.Errorf("params.interval_seconds must be between 1 and 86400")
+		}
+		if c.Params.DurationSeconds != 0 {
+			return fmt.Errorf("params.duration_seconds is not allowed for %s", c.Type)
+		}
+	case CommandTypeRequestPosition:
+		if c.Params != (CommandParams{}) {
+			return fmt.Errorf("%s takes no params", c.Type)
+		}
+	case CommandTypeBuzzer:
+		if c.Params.DurationSeconds < 0 || c.Params.DurationSeconds > 60 {
+			return fmt.Errorf("params.duration_seconds must be at most 60")
+		}
+		if c.Params.IntervalSeconds != 0 {
+			return fmt.Errorf("params.interval_seconds is not allowed for %s", c.Type)
+		}
+	default:
+		return fmt.Errorf("type must be one of: %s, %s, %s", CommandTypeSetInterval, CommandTypeRequestPosition, CommandTypeBuzzer)
+	}
+
+	return nil
+}
+
+// CommandMessage is the payload published to the tracker.
+type CommandMessage struct {
+	ID        string        `json:"id"`
+	Type      string        `json:"type"`
+	Params    CommandParams `json:"params"`
+	ExpiresAt int64         `json:"expires_at"`
+}
+
+// CommandAck is the tracker's reply on /fleet/vehicle/{id}/command/ack.
+// Status is "ok" or "error", with Error describing the failure.
+type CommandAck struct {
+	ID     string `json:"id"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
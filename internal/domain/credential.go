@@ -0,0 +1,72 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	CredentialTypeDevice  = "device"
+	CredentialTypePartner = "partner"
+)
+
+// IngestCredential authenticates HTTP ingestion. A device credential may only
+// report its own vehicle; a partner credential may report every vehicle in
+// its group.
+type IngestCredential struct {
+	ID         string     `json:"id" db:"id"`
+	Name       string     `json:"name" db:"name"`
+	Type       string     `json:"type" db:"type"`
+	VehicleID  string     `json:"vehicle_id,omitempty" db:"vehicle_id"`
+	GroupID    string     `json:"group_id,omitempty" db:"group_id"`
+	Token      string     `json:"token,omitempty" db:"-"`
+	LastUsedAt *time.Time `json:"last_used_at" db:"last_used_at"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+
+	// VehicleIDs are the members of a partner's group, loaded when the
+	// credential is authenticated.
+	VehicleIDs []string `json:"-" db:"-"`
+}
+
+func (c *IngestCredential) Validate() error {
+	if c.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if len(c.Name) > 100 {
+		return fmt.Errorf("name must be at most 100 characters")
+	}
+
+	switch c.Type {
+	case CredentialTypeDevice:
+		if c.VehicleID == "" || len(c.VehicleID) > 50 {
+			return fmt.Errorf("vehicle_id must be 1 to 50 characters")
+		}
+		if c.GroupID != "" {
+			return fmt.Errorf("group_id is only allowed for partner credentials")
+		}
+	case CredentialTypePartner:
+		if c.GroupID == "" {
+			return fmt.Errorf("group_id is required")
+		}
+		if c.VehicleID != "" {
+			return fmt.Errorf("vehicle_id is only allowed for device credentials")
+		}
+	default:
+		return fmt.Errorf("type must be one of: %s, %s", CredentialTypeDevice, CredentialTypePartner)
+	}
+
+	return nil
+}
+
+// Allows reports whether the credential may report locations of vehicleID.
+func (c *IngestCredential) Allows(vehicleID string) bool {
+	if c.Type == CredentialTypeDevice {
+		return vehicleID == c.VehicleID
+	}
+	for _, id := range c.VehicleIDs {
+		if id == vehicleID {
+			return true
+		}
+	}
+	return false
+}
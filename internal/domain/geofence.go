@@ -0,0 +1,162 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/fahri/go-tije/pkg/geofence"
+)
+
+const (
+	GeofenceTypeCircle   = "circle"
+	GeofenceTypePolygon  = "polygon"
+	GeofenceTypeCorridor = "corridor"
+)
+
+const (
+	GeofenceEventEnter = "enter"
+	GeofenceEventExit  = "exit"
+	GeofenceEventDwell = "dwell"
+
+	GeofenceEventRouteDeviation = "route_deviation"
+)
+
+type Geofence struct {
+	ID                 string                `json:"id" db:"id"`
+	Name               string                `json:"name" db:"name"`
+	Type               string                `json:"type" db:"type"`
+	Latitude           float64               `json:"latitude" db:"latitude"`
+	Longitude          float64               `json:"longitude" db:"longitude"`
+	Radius             float64               `json:"radius" db:"radius"`
+	Polygon            geofence.MultiPolygon `json:"polygon,omitempty" db:"polygon"`
+	Line               geofence.Polyline     `json:"line,omitempty" db:"line"`
+	VehicleIDs         []string              `json:"vehicle_ids" db:"vehicle_ids"`
+	GroupIDs           []string              `json:"group_ids" db:"group_ids"`
+	DwellSeconds       int                   `json:"dwell_seconds" db:"dwell_seconds"`
+	HysteresisMeters   float64               `json:"hysteresis_meters" db:"hysteresis_meters"`
+	MinPoints          int                   `json:"min_points" db:"min_points"`
+	MinDurationSeconds int                   `json:"min_duration_seconds" db:"min_duration_seconds"`
+	DeviationSeconds   int                   `json:"deviation_seconds" db:"deviation_seconds"`
+	Schedule           *GeofenceSchedule     `json:"schedule,omitempty" db:"schedule"`
+	Tags               []string              `json:"tags" db:"tags"`
+	Active             bool                  `json:"active" db:"active"`
+	CreatedAt          time.Time             `json:"created_at" db:"created_at"`
+	UpdatedAt          time.Time             `json:"updated_at" db:"updated_at"`
+}
+
+// GeofenceState is the last known position of a vehicle relative to a zone.
+type GeofenceState struct {
+	VehicleID     string    `json:"vehicle_id" db:"vehicle_id"`
+	GeofenceID    string    `json:"geofence_id" db:"geofence_id"`
+	Inside        bool      `json:"inside" db:"inside"`
+	EnteredAt     int64     `json:"entered_at" db:"entered_at"`
+	DwellNotified bool      `json:"dwell_notified" db:"dwell_notified"`
+	PendingCount  int       `json:"pending_count" db:"pending_count"`
+	PendingSince  int64     `json:"pending_since" db:"pending_since"`
+	UpdatedAt     time.Time `json:"updated_at" db:"updated_at"`
+}
+
+func (g *Geofence) Validate() error {
+	if g.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if len(g.Name) > 100 {
+		return fmt.Errorf("name must be at most 100 characters")
+	}
+	if g.DwellSeconds < 0 {
+		return fmt.Errorf("dwell_seconds must not be negative")
+	}
+	if g.HysteresisMeters < 0 || g.MinPoints < 0 || g.MinDurationSeconds < 0 {
+		return fmt.Errorf("hysteresis_meters, min_points and min_duration_seconds must not be negative")
+	}
+	if g.DeviationSeconds < 0 {
+		return fmt.Errorf("deviation_seconds must not be negative")
+	}
+	if g.Schedule != nil {
+		if err := g.Schedule.Validate(); err != nil {
+			return err
+		}
+	}
+
+	switch g.Type {
+	case GeofenceTypeCircle:
+		if !validCoordinate(g.Latitude, g.Longitude) {
+			return fmt.Errorf("latitude must be within [-90, 90] and longitude within [-180, 180]")
+		}
+		if g.Radius <= 0 {
+			return fmt.Errorf("radius must be greater than zero")
+		}
+	case GeofenceTypePolygon:
+		if len(g.Polygon) == 0 {
+			return fmt.Errorf("polygon is required")
+		}
+		for _, polygon := range g.Polygon {
+			for _, ring := range append([]geofence.Ring{polygon.Outer}, polygon.Holes...) {
+				if err := validateRing(ring); err != nil {
+					return err
+				}
+			}
+		}
+	case GeofenceTypeCorridor:
+		if len(g.Line) < 2 {
+			return fmt.Errorf("line needs at least 2 points")
+		}
+		for _, p := range g.Line {
+			if !validCoordinate(p.Latitude, p.Longitude) {
+				return fmt.Errorf("line coordinate out of range: [%f, %f]", p.Longitude, p.Latitude)
+			}
+		}
+		if g.Radius <= 0 {
+			return fmt.Errorf("radius (corridor buffer width) must be greater than zero")
+		}
+	default:
+		return fmt.Errorf("type must be one of: %s, %s, %s", GeofenceTypeCircle, GeofenceTypePolygon, GeofenceTypeCorridor)
+	}
+
+	return nil
+}
+
+func validateRing(ring geofence.Ring) error {
+	if len(ring) < 3 {
+		return fmt.Errorf("polygon rings need at least 3 points")
+	}
+	for _, p := range ring {
+		if !validCoordinate(p.Latitude, p.Longitude) {
+			return fmt.Errorf("polygon coordinate out of range: [%f, %f]", p.Longitude, p.Latitude)
+		}
+	}
+	return nil
+}
+
+func validCoordinate(lat, lon float64) bool {
+	return lat >= -90 && lat <= 90 && lon >= -180 && lon <= 180
+}
+
+// AppliesTo reports whether the zone is evaluated for a vehicle in the given
+// groups. Zones without assigned vehicles or groups apply to everyone, except
+// corridors, which only make sense for the vehicles that drive that route.
+func (g *Geofence) AppliesTo(vehicleID string, groupIDs []string) bool {
+	if len(g.VehicleIDs) == 0 && len(g.GroupIDs) == 0 {
+		return g.Type != GeofenceTypeCorridor
+	}
+	return contains(g.VehicleIDs, vehicleID) || g.AssignedGroup(groupIDs) != ""
+}
+
+// AssignedGroup returns the first of the given groups the zone is assigned to.
+func (g *Geofence) AssignedGroup(groupIDs []string) string {
+	for _, id := range groupIDs {
+		if contains(g.GroupIDs, id) {
+			return id
+		}
+	}
+	return ""
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,44 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	VehicleGroupTypeRoute      = "route"
+	VehicleGroupTypeDepot      = "depot"
+	VehicleGroupTypeContractor = "contractor"
+)
+
+type VehicleGroup struct {
+	ID         string    `json:"id" db:"id"`
+	Name       string    `json:"name" db:"name"`
+	Type       string    `json:"type" db:"type"`
+	VehicleIDs []string  `json:"vehicle_ids" db:"vehicle_ids"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at" db:"updated_at"`
+}
+
+func (g *VehicleGroup) Validate() error {
+	if g.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if len(g.Name) > 100 {
+		return fmt.Errorf("name must be at most 100 characters")
+	}
+
+	switch g.Type {
+	case VehicleGroupTypeRoute, VehicleGroupTypeDepot, VehicleGroupTypeContractor:
+	default:
+		return fmt.Errorf("type must be one of: %s, %s, %s", VehicleGroupTypeRoute, VehicleGroupTypeDepot, VehicleGroupTypeContractor)
+	}
+
+	for _, id := range g.VehicleIDs {
+		if id == "" || len(id) > 50 {
+			return fmt.Errorf("vehicle_ids must be 1 to 50 characters")
+		}
+	}
+
+	return nil
+}
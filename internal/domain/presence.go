@@ -0,0 +1,19 @@
+package domain
+
+import "time"
+
+const (
+	PresenceOnline  = "online"
+	PresenceOffline = "offline"
+	PresenceUnknown = "unknown"
+)
+
+// VehiclePresence tells whether a vehicle's tracker is connected. Status
+// comes from the tracker's status topic and its Last Will; LastSeen is the
+// last time the tracker itself was heard from, by status or location.
+type VehiclePresence struct {
+	VehicleID string     `json:"vehicle_id" db:"vehicle_id"`
+	Status    string     `json:"status" db:"status"`
+	Since     *time.Time `json:"since" db:"changed_at"`
+	LastSeen  *time.Time `json:"last_seen" db:"last_seen_at"`
+}
@@ -0,0 +1,134 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// GeofenceSchedule limits when a zone is evaluated. A zone without a
+// schedule is always active.
+type GeofenceSchedule struct {
+	Timezone string           `json:"timezone"`
+	Windows  []ScheduleWindow `json:"windows"`
+}
+
+// ScheduleWindow is active on the listed weekdays ("mon" .. "sun", every day
+// when empty) from Start to End ("HH:MM"). An End before Start wraps past
+// midnight into the following day; an End equal to Start makes the window a
+// full 24 hours, so "00:00"-"00:00" covers the whole of each listed day.
+type ScheduleWindow struct {
+	Weekdays []string `json:"weekdays"`
+	Start    string   `json:"start"`
+	End      string   `json:"end"`
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+var locations sync.Map
+
+// Active reports whether the unix timestamp falls inside one of the windows.
+func (s *GeofenceSchedule) Active(timestamp int64) bool {
+	if s == nil || len(s.Windows) == 0 {
+		return true
+	}
+
+	loc, err := loadLocation(s.Timezone)
+	if err != nil {
+		return false
+	}
+
+	t := time.Unix(timestamp, 0).In(loc)
+	minute := t.Hour()*60 + t.Minute()
+	today := t.Weekday()
+	yesterday := (today + 6) % 7
+
+	for _, window := range s.Windows {
+		start, _ := parseClock(window.Start)
+		end, _ := parseClock(window.End)
+
+		if start < end {
+			if window.on(today) && minute >= start && minute < end {
+				return true
+			}
+			continue
+		}
+
+		if (window.on(today) && minute >= start) || (window.on(yesterday) && minute < end) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (s *GeofenceSchedule) Validate() error {
+	if _, err := loadLocation(s.Timezone); err != nil {
+		return fmt.Errorf("schedule timezone %q is invalid", s.Timezone)
+	}
+	if len(s.Windows) == 0 {
+		return fmt.Errorf("schedule needs at least one window")
+	}
+
+	for _, window := range s.Windows {
+		for _, day := range window.Weekdays {
+			if _, ok := weekdays[strings.ToLower(day)]; !ok {
+				return fmt.Errorf("schedule weekday %q is invalid, use mon..sun", day)
+			}
+		}
+		if _, err := parseClock(window.Start); err != nil {
+			return fmt.Errorf("schedule start %q is invalid, use HH:MM", window.Start)
+		}
+		if _, err := parseClock(window.End); err != nil {
+			return fmt.Errorf("schedule end %q is invalid, use HH:MM", window.End)
+		}
+	}
+
+	return nil
+}
+
+func (w ScheduleWindow) on(day time.Weekday) bool {
+	if len(w.Weekdays) == 0 {
+		return true
+	}
+	for _, name := range w.Weekdays {
+		if weekdays[strings.ToLower(name)] == day {
+			return true
+		}
+	}
+	return false
+}
+
+// parseClock returns the minutes since midnight of an "HH:MM" string.
+func parseClock(clock string) (int, error) {
+	t, err := time.Parse("15:04", clock)
+	if err != nil {
+		return 0, err
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+func loadLocation(name string) (*time.Location, error) {
+	if name == "" {
+		return time.UTC, nil
+	}
+	if loc, ok := locations.Load(name); ok {
+		return loc.(*time.Location), nil
+	}
+
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, err
+	}
+	locations.Store(name, loc)
+	return loc, nil
+}
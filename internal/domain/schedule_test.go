@@ -0,0 +1,47 @@
+package domain
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGeofenceScheduleActive(t *testing.T) {
+	// 6 May 2024 is a Monday.
+	at := func(value string) int64 {
+		t, err := time.Parse("2006-01-02 15:04", value)
+		if err != nil {
+			panic(err)
+		}
+		return t.Unix()
+	}
+
+	tests := []struct {
+		name   string
+		window ScheduleWindow
+		at     int64
+		want   bool
+	}{
+		{"daytime inside", ScheduleWindow{Start: "08:00", End: "17:00"}, at("2024-05-06 12:00"), true},
+		{"daytime at end", ScheduleWindow{Start: "08:00", End: "17:00"}, at("2024-05-06 17:00"), false},
+		{"overnight before midnight", ScheduleWindow{Weekdays: []string{"mon"}, Start: "22:00", End: "06:00"}, at("2024-05-06 23:00"), true},
+		{"overnight after midnight", ScheduleWindow{Weekdays: []string{"mon"}, Start: "22:00", End: "06:00"}, at("2024-05-07 05:59"), true},
+		{"overnight next evening", ScheduleWindow{Weekdays: []string{"mon"}, Start: "22:00", End: "06:00"}, at("2024-05-07 23:00"), false},
+		{"equal midnight covers the day", ScheduleWindow{Start: "00:00", End: "00:00"}, at("2024-05-06 00:00"), true},
+		{"equal midnight late evening", ScheduleWindow{Start: "00:00", End: "00:00"}, at("2024-05-06 23:59"), true},
+		{"equal midnight only listed day", ScheduleWindow{Weekdays: []string{"mon"}, Start: "00:00", End: "00:00"}, at("2024-05-07 00:00"), false},
+		{"equal start and end spans 24 hours", ScheduleWindow{Weekdays: []string{"mon"}, Start: "08:00", End: "08:00"}, at("2024-05-07 07:59"), true},
+		{"equal start and end ends after 24 hours", ScheduleWindow{Weekdays: []string{"mon"}, Start: "08:00", End: "08:00"}, at("2024-05-07 08:00"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule := &GeofenceSchedule{Windows: []ScheduleWindow{tt.window}}
+			if err := schedule.Validate(); err != nil {
+				t.Fatalf("Validate: %v", err)
+			}
+			if got := schedule.Active(tt.at); got != tt.want {
+				t.Errorf("Active = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+)
+
+// RejectReason classifies why an incoming location was not accepted.
+type RejectReason string
+
+const (
+	RejectInvalidPayload     RejectReason = "invalid_payload"
+	RejectMissingVehicleID   RejectReason = "missing_vehicle_id"
+	RejectInvalidVehicleID   RejectReason = "invalid_vehicle_id"
+	RejectInvalidCoordinates RejectReason = "invalid_coordinates"
+	RejectNullIsland         RejectReason = "null_island"
+	RejectMissingTimestamp   RejectReason = "missing_timestamp"
+	RejectFutureTimestamp    RejectReason = "future_timestamp"
+	RejectStaleTimestamp     RejectReason = "stale_timestamp"
+	RejectInvalidTelemetry   RejectReason = "invalid_telemetry"
+	// RejectStorageFailed marks a validated location the database refused.
+	RejectStorageFailed RejectReason = "storage_failed"
+)
+
+// ValidationError is returned for a location that was rejected.
+type ValidationError struct {
+	Reason  RejectReason
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Reason, e.Message)
+}
+
+func reject(reason RejectReason, format string, args ...interface{}) error {
+	return &ValidationError{Reason: reason, Message: fmt.Sprintf(format, args...)}
+}
+
+// InvalidLocation is a rejected message kept for inspection.
+type InvalidLocation struct {
+	ID        string       `json:"id" db:"id"`
+	VehicleID string       `json:"vehicle_id,omitempty" db:"vehicle_id"`
+	Reason    RejectReason `json:"reason" db:"reason"`
+	Message   string       `json:"message" db:"message"`
+	Payload   []byte       `json:"-" db:"payload"`
+	CreatedAt time.Time    `json:"created_at" db:"created_at"`
+}
+
+// Validate checks a location against now, rejecting timestamps more than
+// maxFuture ahead or maxAge behind. A zero limit disables that check.
+func (m *LocationMessage) Validate(now time.Time, maxFuture, maxAge time.Duration) error {
+	if m.VehicleID == "" {
+		return reject(RejectMissingVehicleID, "vehicle_id is required")
+	}
+	if len(m.VehicleID) > 50 {
+		return reject(RejectInvalidVehicleID, "vehicle_id must be at most 50 characters")
+	}
+	if len(m.MessageID) > 100 {
+		return reject(RejectInvalidPayload, "message_id must be at most 100 characters")
+	}
+
+	if !validCoordinate(m.Latitude, m.Longitude) {
+		return reject(RejectInvalidCoordinates, "latitude %v, longitude %v out of range", m.Latitude, m.Longitude)
+	}
+	if m.Latitude == 0 && m.Longitude == 0 {
+		return reject(RejectNullIsland, "latitude and longitude are both zero")
+	}
+
+	if m.Timestamp <= 0 {
+		return reject(RejectMissingTimestamp, "timestamp is required")
+	}
+	if maxFuture > 0 && m.Timestamp > now.Add(maxFuture).Unix() {
+		return reject(RejectFutureTimestamp, "timestamp %d is ahead of server time", m.Timestamp)
+	}
+	if maxAge > 0 && m.Timestamp < now.Add(-maxAge).Unix() {
+		return reject(RejectStaleTimestamp, "timestamp %d is older than %s", m.Timestamp, maxAge)
+	}
+
+	return m.Telemetry.Validate()
+}
+
+func (t *Telemetry) Validate() error {
+	switch {
+	case t.Speed != nil && (*t.Speed < 0 || *t.Speed > 400):
+		return reject(RejectInvalidTelemetry, "speed must be within [0, 400]")
+	case t.Heading != nil && (*t.Heading < 0 || *t.Heading > 360):
+		return reject(RejectInvalidTelemetry, "heading must be within [0, 360]")
+	case t.HDOP != nil && *t.HDOP < 0:
+		return reject(RejectInvalidTelemetry, "hdop must not be negative")
+	case t.Accuracy != nil && *t.Accuracy < 0:
+		return reject(RejectInvalidTelemetry, "accuracy must not be negative")
+	case t.Satellites != nil && *t.Satellites < 0:
+		return reject(RejectInvalidTelemetry, "satellites must not be negative")
+	case t.FixQuality != nil && (*t.FixQuality < 0 || *t.FixQuality > 8):
+		return reject(RejectInvalidTelemetry, "fix_quality must be within [0, 8]")
+	case t.Odometer != nil && *t.Odometer < 0:
+		return reject(RejectInvalidTelemetry, "odometer must not be negative")
+	case t.BatteryVoltage != nil && *t.BatteryVoltage < 0:
+		return reject(RejectInvalidTelemetry, "battery_voltage must not be negative")
+	}
+	return nil
+}
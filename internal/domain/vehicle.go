@@ -1,16 +1,27 @@
 package domain
 
 import (
+	"errors"
 	"time"
 )
 
+// ErrDuplicateLocation is returned for a location that was already ingested.
+var ErrDuplicateLocation = errors.New("duplicate location")
+
+// ErrLateLocation is returned by geofence evaluation for a location older than
+// the last one evaluated for its vehicle.
+var ErrLateLocation = errors.New("late location")
+
 type VehicleLocation struct {
 	ID        string    `json:"id" db:"id"`
 	VehicleID string    `json:"vehicle_id" db:"vehicle_id"`
 	Latitude  float64   `json:"latitude" db:"latitude"`
 	Longitude float64   `json:"longitude" db:"longitude"`
 	Timestamp int64     `json:"timestamp" db:"timestamp"`
+	MessageID string    `json:"message_id,omitempty" db:"message_id"`
+	Backfill  bool      `json:"backfill,omitempty" db:"backfill"`
 	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	Telemetry
 }
 
 type LocationMessage struct {
@@ -18,24 +29,39 @@ type LocationMessage struct {
 	Latitude  float64 `json:"latitude"`
 	Longitude float64 `json:"longitude"`
 	Timestamp int64   `json:"timestamp"`
+	MessageID string  `json:"message_id,omitempty"`
+	Telemetry
+}
+
+// Telemetry holds the optional tracker readings sent alongside a position.
+// Every field is a pointer so that a reading the device did not send stays
+// distinguishable from a zero value; older devices omit all of them.
+type Telemetry struct {
+	Speed          *float64 `json:"speed,omitempty" db:"speed"`                     // km/h
+	Heading        *float64 `json:"heading,omitempty" db:"heading"`                 // degrees from true north
+	Altitude       *float64 `json:"altitude,omitempty" db:"altitude"`               // meters above sea level
+	HDOP           *float64 `json:"hdop,omitempty" db:"hdop"`                       // horizontal dilution of precision
+	Accuracy       *float64 `json:"accuracy,omitempty" db:"accuracy"`               // estimated horizontal error in meters
+	Satellites     *int     `json:"satellites,omitempty" db:"satellites"`           // satellites in use
+	FixQuality     *int     `json:"fix_quality,omitempty" db:"fix_quality"`         // NMEA GGA fix quality, 0 = no fix
+	Ignition       *bool    `json:"ignition,omitempty" db:"ignition"`               // ignition on/off
+	Odometer       *float64 `json:"odometer,omitempty" db:"odometer"`               // kilometers
+	BatteryVoltage *float64 `json:"battery_voltage,omitempty" db:"battery_voltage"` // volts
 }
 
 type GeofenceEvent struct {
-	VehicleID string   `json:"vehicle_id"`
-	Event     string   `json:"event"`
-	Location  Location `json:"location"`
-	Timestamp int64    `json:"timestamp"`
+	VehicleID    string   `json:"vehicle_id"`
+	GeofenceID   string   `json:"geofence_id"`
+	GeofenceName string   `json:"geofence_name"`
+	GroupID      string   `json:"group_id,omitempty"`
+	GroupName    string   `json:"group_name,omitempty"`
+	Event        string   `json:"event"`
+	Location     Location `json:"location"`
+	Timestamp    int64    `json:"timestamp"`
+	Duration     int64    `json:"duration,omitempty"`
 }
 
 type Location struct {
 	Latitude  float64 `json:"latitude"`
 	Longitude float64 `json:"longitude"`
 }
-
-type Geofence struct {
-	ID        string
-	Name      string
-	Latitude  float64
-	Longitude float64
-	Radius    float64
-}
\ No newline at end of file
@@ -0,0 +1,89 @@
+package gateway
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+var errShortFrame = errors.New("frame too short")
+
+// frameReader reads big-endian fields from a frame. Reading past the end sets
+// err and yields zeros, so a decoder can check err once at the end.
+type frameReader struct {
+	b   []byte
+	err error
+}
+
+func (r *frameReader) next(n int) []byte {
+	if r.err != nil || n < 0 || len(r.b) < n {
+		if r.err == nil {
+			r.err = errShortFrame
+		}
+		return make([]byte, max(n, 0))
+	}
+	v := r.b[:n]
+	r.b = r.b[n:]
+	return v
+}
+
+func (r *frameReader) u8() uint8 {
+	return r.next(1)[0]
+}
+
+func (r *frameReader) u16() uint16 {
+	return binary.BigEndian.Uint16(r.next(2))
+}
+
+func (r *frameReader) u32() uint32 {
+	return binary.BigEndian.Uint32(r.next(4))
+}
+
+func (r *frameReader) u64() uint64 {
+	return binary.BigEndian.Uint64(r.next(8))
+}
+
+// uint reads an unsigned field of 1, 2, 4 or 8 bytes.
+func (r *frameReader) uint(size int) uint64 {
+	switch size {
+	case 1:
+		return uint64(r.u8())
+	case 2:
+		return uint64(r.u16())
+	case 4:
+		return uint64(r.u32())
+	default:
+		return r.u64()
+	}
+}
+
+// crc16IBM is CRC-16/ARC, used by Teltonika AVL packets.
+func crc16IBM(data []byte) uint16 {
+	var crc uint16
+	for _, b := range data {
+		crc ^= uint16(b)
+		for i := 0; i < 8; i++ {
+			if crc&1 != 0 {
+				crc = crc>>1 ^ 0xA001
+			} else {
+				crc >>= 1
+			}
+		}
+	}
+	return crc
+}
+
+// crc16ITU is CRC-16/X-25, used by GT06 packets.
+func crc16ITU(data []byte) uint16 {
+	crc := uint16(0xFFFF)
+	for _, b := range data {
+		crc ^= uint16(b)
+		for i := 0; i < 8; i++ {
+			if crc&1 != 0 {
+				crc = crc>>1 ^ 0x8408
+			} else {
+				crc >>= 1
+			}
+		}
+	}
+	return ^crc
+}
@@ -0,0 +1,50 @@
+package gateway
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+// decodeHex decodes a frame written as hex, ignoring spaces.
+func decodeHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b := make([]byte, 0, len(s)/2)
+	for _, c := range s {
+		if c != ' ' {
+			b = append(b, byte(c))
+		}
+	}
+	out, err := hex.DecodeString(string(b))
+	if err != nil {
+		t.Fatalf("invalid hex %q: %v", s, err)
+	}
+	return out
+}
+
+func TestCRC16(t *testing.T) {
+	// Check values from the CRC catalogue for the input "123456789".
+	check := []byte("123456789")
+	if got := crc16IBM(check); got != 0xBB3D {
+		t.Errorf("crc16IBM = %04X, want BB3D", got)
+	}
+	if got := crc16ITU(check); got != 0x906E {
+		t.Errorf("crc16ITU = %04X, want 906E", got)
+	}
+}
+
+func TestFrameReaderShortRead(t *testing.T) {
+	r := &frameReader{b: []byte{0x01, 0x02, 0x03}}
+	if got := r.u16(); got != 0x0102 || r.err != nil {
+		t.Fatalf("u16 = %04X, %v", got, r.err)
+	}
+	if got := r.u32(); got != 0 || r.err != errShortFrame {
+		t.Errorf("u32 past the end = %X, %v; want 0, %v", got, r.err, errShortFrame)
+	}
+	// Once short, later reads yield zeros even if bytes remain.
+	if got := r.u8(); got != 0 || r.err != errShortFrame {
+		t.Errorf("u8 after a short read = %X, %v", got, r.err)
+	}
+	if got := r.next(-1); len(got) != 0 {
+		t.Errorf("next(-1) returned %d bytes", len(got))
+	}
+}
@@ -0,0 +1,220 @@
+package gateway
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/fahri/go-tije/internal/domain"
+)
+
+// GT06 protocol numbers.
+const (
+	gt06Login       = 0x01
+	gt06Location    = 0x12
+	gt06Heartbeat   = 0x13
+	gt06Alarm       = 0x16
+	gt06LocationExt = 0x22
+	gt06AlarmExt    = 0x26
+)
+
+type gt06Packet struct {
+	protocol byte
+	info     []byte
+	serial   uint16
+}
+
+// serveGT06 runs a GT06 session. The device logs in with its IMEI, then
+// sends locations, heartbeats and alarms; login, heartbeat and alarm packets
+// are answered with a response echoing the protocol number and serial.
+func (s *Server) serveGT06(conn net.Conn) {
+	r := bufio.NewReader(conn)
+
+	s.extendDeadline(conn)
+	packet, err := readGT06Packet(r)
+	if err != nil {
+		log.Printf("GT06 login from %s failed: %v", conn.RemoteAddr(), err)
+		return
+	}
+	if packet.protocol != gt06Login || len(packet.info) < 8 {
+		log.Printf("GT06 connection from %s did not start with a login packet", conn.RemoteAddr())
+		return
+	}
+
+	imei := decodeGT06IMEI(packet.info[:8])
+	if _, err := conn.Write(gt06Response(packet)); err != nil {
+		return
+	}
+	log.Printf("GT06 device %s connected from %s", imei, conn.RemoteAddr())
+
+	// The ignition state comes from heartbeats and is attached to the
+	// locations that follow.
+	var ignition *bool
+	for {
+		s.extendDeadline(conn)
+		packet, err := readGT06Packet(r)
+		if err == io.EOF {
+			log.Printf("GT06 device %s disconnected", imei)
+			return
+		}
+		if err != nil {
+			log.Printf("GT06 device %s: %v", imei, err)
+			return
+		}
+
+		switch packet.protocol {
+		case gt06Location, gt06LocationExt, gt06Alarm, gt06AlarmExt:
+			message, acc, err := decodeGT06Location(imei, packet)
+			if err != nil {
+				log.Printf("GT06 device %s: %v", imei, err)
+				continue
+			}
+			if acc != nil {
+				ignition = acc
+			}
+			if message != nil {
+				message.Ignition = ignition
+				s.handleAll([]*domain.LocationMessage{message})
+			}
+		case gt06Heartbeat:
+			if len(packet.info) > 0 {
+				acc := packet.info[0]&0x02 != 0
+				ignition = &acc
+			}
+		default:
+			log.Printf("GT06 device %s: ignoring protocol 0x%02X", imei, packet.protocol)
+			continue
+		}
+
+		if packet.protocol == gt06Heartbeat || packet.protocol == gt06Alarm || packet.protocol == gt06AlarmExt {
+			if _, err := conn.Write(gt06Response(packet)); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// readGT06Packet reads a 0x7878 (one-byte length) or 0x7979 (two-byte length)
+// packet and checks its CRC and stop bits.
+func readGT06Packet(r *bufio.Reader) (*gt06Packet, error) {
+	start := make([]byte, 2)
+	if _, err := io.ReadFull(r, start); err != nil {
+		return nil, err
+	}
+
+	var header []byte
+	switch {
+	case start[0] == 0x78 && start[1] == 0x78:
+		header = make([]byte, 1)
+	case start[0] == 0x79 && start[1] == 0x79:
+		header = make([]byte, 2)
+	default:
+		return nil, fmt.Errorf("invalid start bits %X", start)
+	}
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+
+	length := int(header[0])
+	if len(header) == 2 {
+		length = int(binary.BigEndian.Uint16(header))
+	}
+	// Protocol number, serial and CRC take five bytes.
+	if length < 5 {
+		return nil, fmt.Errorf("invalid packet length %d", length)
+	}
+
+	body := make([]byte, length+2)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, err
+	}
+	if body[length] != 0x0D || body[length+1] != 0x0A {
+		return nil, fmt.Errorf("invalid stop bits")
+	}
+
+	crc := binary.BigEndian.Uint16(body[length-2:])
+	if crc16ITU(append(header, body[:length-2]...)) != crc {
+		return nil, fmt.Errorf("CRC mismatch")
+	}
+
+	return &gt06Packet{
+		protocol: body[0],
+		info:     body[1 : length-4],
+		serial:   binary.BigEndian.Uint16(body[length-4:]),
+	}, nil
+}
+
+func gt06Response(packet *gt06Packet) []byte {
+	b := []byte{0x05, packet.protocol, byte(packet.serial >> 8), byte(packet.serial)}
+	crc := crc16ITU(b)
+	b = append([]byte{0x78, 0x78}, b...)
+	return append(b, byte(crc>>8), byte(crc), 0x0D, 0x0A)
+}
+
+// decodeGT06IMEI decodes the 8-byte BCD terminal ID into the 15-digit IMEI.
+func decodeGT06IMEI(b []byte) string {
+	imei := fmt.Sprintf("%X", b)
+	return strings.TrimLeft(imei, "0")
+}
+
+// decodeGT06Location decodes the GPS block shared by location and alarm
+// packets. It also returns the ACC (ignition) state when the packet carries
+// one. The message is nil when the device had no GPS fix.
+func decodeGT06Location(imei string, packet *gt06Packet) (*domain.LocationMessage, *bool, error) {
+	r := &frameReader{b: packet.info}
+
+	date := r.next(6)
+	gpsInfo := r.u8()
+	lat := float64(r.u32()) / 1800000
+	lon := float64(r.u32()) / 1800000
+	speed := float64(r.u8())
+	courseStatus := r.u16()
+
+	var acc *bool
+	switch packet.protocol {
+	case gt06LocationExt:
+		r.next(8) // MCC, MNC, LAC, cell ID
+		on := r.u8() != 0
+		acc = &on
+	case gt06Alarm, gt06AlarmExt:
+		// The LBS length byte counts itself.
+		r.next(max(int(r.u8())-1, 0))
+		on := r.u8()&0x02 != 0
+		acc = &on
+	}
+	if r.err != nil {
+		return nil, nil, fmt.Errorf("invalid location packet: %v", r.err)
+	}
+
+	if courseStatus&0x1000 == 0 {
+		return nil, acc, nil
+	}
+	if courseStatus&0x0400 == 0 {
+		lat = -lat
+	}
+	if courseStatus&0x0800 != 0 {
+		lon = -lon
+	}
+
+	timestamp := time.Date(2000+int(date[0]), time.Month(date[1]), int(date[2]),
+		int(date[3]), int(date[4]), int(date[5]), 0, time.UTC)
+	heading := float64(courseStatus & 0x03FF)
+	satellites := int(gpsInfo & 0x0F)
+
+	message := &domain.LocationMessage{
+		VehicleID: imei,
+		Latitude:  lat,
+		Longitude: lon,
+		Timestamp: timestamp.Unix(),
+	}
+	message.Speed = &speed
+	message.Heading = &heading
+	message.Satellites = &satellites
+
+	return message, acc, nil
+}
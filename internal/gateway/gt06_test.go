@@ -0,0 +1,213 @@
+package gateway
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"math"
+	"testing"
+	"time"
+)
+
+// Sample packets from the GT06 protocol documentation.
+const (
+	gt06LoginSample         = "7878 0D 01 0123456789012345 0001 8CDD 0D0A"
+	gt06LoginResponseSample = "7878 05 01 0001 D9DC 0D0A"
+
+	gt06LocationSample = "7878 1F 12 0B081D112E10 CF 027AC7EB 0C465849 00 148F" +
+		" 01CC 00 287D 001FB8 0003 8081 0D0A"
+)
+
+// gt06Frame wraps a protocol number, information content and serial in a
+// 0x7878 packet with its CRC.
+func gt06Frame(protocol byte, info []byte, serial uint16) []byte {
+	b := []byte{byte(len(info) + 5), protocol}
+	b = append(b, info...)
+	b = append(b, byte(serial>>8), byte(serial))
+	crc := crc16ITU(b)
+	b = append([]byte{0x78, 0x78}, b...)
+	return append(b, byte(crc>>8), byte(crc), 0x0D, 0x0A)
+}
+
+func readGT06(b []byte) (*gt06Packet, error) {
+	return readGT06Packet(bufio.NewReader(bytes.NewReader(b)))
+}
+
+func TestReadGT06Packet(t *testing.T) {
+	packet, err := readGT06(decodeHex(t, gt06LoginSample))
+	if err != nil {
+		t.Fatalf("login: %v", err)
+	}
+	if packet.protocol != gt06Login || packet.serial != 1 {
+		t.Errorf("login: protocol 0x%02X serial %d", packet.protocol, packet.serial)
+	}
+	if imei := decodeGT06IMEI(packet.info); imei != "123456789012345" {
+		t.Errorf("IMEI = %q, want 123456789012345", imei)
+	}
+	if got, want := gt06Response(packet), decodeHex(t, gt06LoginResponseSample); !bytes.Equal(got, want) {
+		t.Errorf("login response = %X, want %X", got, want)
+	}
+
+	// The same login with a two-byte length.
+	long := decodeHex(t, "7979 000D 01 0123456789012345 0001")
+	crc := crc16ITU(long[2:])
+	long = append(long, byte(crc>>8), byte(crc), 0x0D, 0x0A)
+	if packet, err := readGT06(long); err != nil || decodeGT06IMEI(packet.info) != "123456789012345" {
+		t.Errorf("0x7979 login: %+v, %v", packet, err)
+	}
+}
+
+func TestReadGT06PacketInvalid(t *testing.T) {
+	login := decodeHex(t, gt06LoginSample)
+	corrupt := func(i int) []byte {
+		b := append([]byte(nil), login...)
+		b[i] ^= 0x01
+		return b
+	}
+
+	tests := []struct {
+		name  string
+		frame []byte
+		want  string
+	}{
+		{"empty", nil, io.EOF.Error()},
+		{"start bits only", login[:2], io.EOF.Error()},
+		{"truncated", login[:10], io.ErrUnexpectedEOF.Error()},
+		{"missing stop bits", login[:len(login)-2], io.ErrUnexpectedEOF.Error()},
+		{"bad start bits", corrupt(0), "invalid start bits 7978"},
+		{"bad stop bits", corrupt(len(login) - 1), "invalid stop bits"},
+		{"corrupt IMEI", corrupt(6), "CRC mismatch"},
+		{"corrupt CRC", corrupt(len(login) - 3), "CRC mismatch"},
+		{"length too short", decodeHex(t, "7878 04 01 0001 0D0A"), "invalid packet length 4"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := readGT06(tt.frame); err == nil || err.Error() != tt.want {
+				t.Errorf("err = %v, want %s", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeGT06Location(t *testing.T) {
+	packet, err := readGT06(decodeHex(t, gt06LocationSample))
+	if err != nil {
+		t.Fatalf("readGT06Packet: %v", err)
+	}
+	message, acc, err := decodeGT06Location("123456789012345", packet)
+	if err != nil {
+		t.Fatalf("decodeGT06Location: %v", err)
+	}
+	if acc != nil {
+		t.Errorf("0x12 packet returned ACC %v", *acc)
+	}
+
+	want := time.Date(2011, 8, 29, 17, 46, 16, 0, time.UTC).Unix()
+	if message.VehicleID != "123456789012345" || message.Timestamp != want {
+		t.Errorf("vehicle %q at %d, want %d", message.VehicleID, message.Timestamp, want)
+	}
+	if math.Abs(message.Latitude-23.1116683) > 1e-7 || math.Abs(message.Longitude-114.4092850) > 1e-7 {
+		t.Errorf("position %v, %v", message.Latitude, message.Longitude)
+	}
+	if *message.Speed != 0 || *message.Heading != 143 || *message.Satellites != 15 {
+		t.Errorf("speed %v heading %v satellites %v", *message.Speed, *message.Heading, *message.Satellites)
+	}
+}
+
+func TestDecodeGT06LocationVariants(t *testing.T) {
+	gps := func(courseStatus string) string {
+		return "180506070809 C9 00AAAA14 0B76C450 28 " + courseStatus
+	}
+
+	tests := []struct {
+		name      string
+		protocol  byte
+		info      string
+		noFix     bool
+		lat, lon  float64
+		ignition  *bool
+		wantError bool
+	}{
+		{"south west", gt06Location, gps("180A"), false, -6.2137, -106.8516, nil, false},
+		{"north east", gt06Location, gps("140A"), false, 6.2137, 106.8516, nil, false},
+		{"no fix", gt06Location, gps("040A"), true, 0, 0, nil, false},
+		{"extended with ACC on", gt06LocationExt, gps("140A") + " 01FE 0A 1234 00ABCD 01", false, 6.2137, 106.8516, ptrBool(true), false},
+		{"alarm with ACC off", gt06Alarm, gps("140A") + " 09 01CC 00 287D 001FB8 40", false, 6.2137, 106.8516, ptrBool(false), false},
+		{"truncated", gt06Location, "180506070809 C9 00AAAA14", false, 0, 0, nil, true},
+		{"extended without ACC", gt06LocationExt, gps("140A") + " 01FE 0A 1234 00ABCD", false, 0, 0, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			packet := &gt06Packet{protocol: tt.protocol, info: decodeHex(t, tt.info)}
+			message, acc, err := decodeGT06Location("123456789012345", packet)
+			if (err != nil) != tt.wantError {
+				t.Fatalf("err = %v, wantError %v", err, tt.wantError)
+			}
+			if tt.wantError {
+				return
+			}
+			if (acc == nil) != (tt.ignition == nil) || (acc != nil && *acc != *tt.ignition) {
+				t.Errorf("ACC = %v, want %v", acc, tt.ignition)
+			}
+			if tt.noFix {
+				if message != nil {
+					t.Errorf("got a location without a GPS fix: %+v", message)
+				}
+				return
+			}
+			if math.Abs(message.Latitude-tt.lat) > 1e-4 || math.Abs(message.Longitude-tt.lon) > 1e-4 {
+				t.Errorf("position %v, %v, want %v, %v", message.Latitude, message.Longitude, tt.lat, tt.lon)
+			}
+			if message.Timestamp != time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC).Unix() || *message.Heading != 10 || *message.Satellites != 9 || *message.Speed != 40 {
+				t.Errorf("telemetry %+v", message.Telemetry)
+			}
+		})
+	}
+}
+
+func ptrBool(v bool) *bool { return &v }
+
+func TestServeGT06(t *testing.T) {
+	rec := &recorder{}
+	conn, done := session(t, (*Server).serveGT06, rec.handle)
+
+	login := decodeHex(t, gt06LoginSample)
+	if got, want := exchange(t, conn, login, 10), decodeHex(t, gt06LoginResponseSample); !bytes.Equal(got, want) {
+		t.Fatalf("login response = %X, want %X", got, want)
+	}
+
+	// A heartbeat with ACC on is answered and sets the ignition of the
+	// location that follows.
+	heartbeat := gt06Frame(gt06Heartbeat, []byte{0x46, 0x06, 0x04, 0x00, 0x02}, 2)
+	if got, want := exchange(t, conn, heartbeat, 10), gt06Response(&gt06Packet{protocol: gt06Heartbeat, serial: 2}); !bytes.Equal(got, want) {
+		t.Errorf("heartbeat response = %X, want %X", got, want)
+	}
+	if _, err := conn.Write(decodeHex(t, gt06LocationSample)); err != nil {
+		t.Fatalf("write location: %v", err)
+	}
+	done()
+
+	messages := rec.received()
+	if len(messages) != 1 {
+		t.Fatalf("handled %d locations, want 1", len(messages))
+	}
+	if m := messages[0]; m.VehicleID != "123456789012345" || m.Ignition == nil || !*m.Ignition {
+		t.Errorf("location from %q with ignition %v", m.VehicleID, m.Ignition)
+	}
+}
+
+func TestServeGT06RequiresLogin(t *testing.T) {
+	rec := &recorder{}
+	conn, done := session(t, (*Server).serveGT06, rec.handle)
+
+	conn.Write(decodeHex(t, gt06LocationSample))
+	// The gateway hangs up without replying.
+	if n, err := conn.Read(make([]byte, 10)); err == nil {
+		t.Errorf("read %d bytes of reply", n)
+	}
+	done()
+	if len(rec.received()) != 0 {
+		t.Error("handled a location sent before login")
+	}
+}
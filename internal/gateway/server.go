@@ -0,0 +1,158 @@
+// Package gateway accepts raw TCP connections from GPS trackers that speak
+// vendor binary protocols (Teltonika Codec 8/8E and GT06) and turns their
+// records into location messages.
+package gateway
+
+import (
+	"context"
+	"log"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/fahri/go-tije/internal/config"
+	"github.com/fahri/go-tije/internal/domain"
+)
+
+// Handler processes one decoded location. It returns an error only when the
+// location was not handled and the device should send it again.
+type Handler func(ctx context.Context, message *domain.LocationMessage) error
+
+type Server struct {
+	handler        Handler
+	idleTimeout    time.Duration
+	messageTimeout time.Duration
+
+	mu        sync.Mutex
+	listeners []net.Listener
+	conns     map[net.Conn]struct{}
+	closed    bool
+	wg        sync.WaitGroup
+}
+
+func NewServer(cfg *config.GatewayConfig, handler Handler) *Server {
+	return &Server{
+		handler:        handler,
+		idleTimeout:    cfg.IdleTimeout,
+		messageTimeout: cfg.MessageTimeout,
+		conns:          make(map[net.Conn]struct{}),
+	}
+}
+
+// ListenTeltonika accepts Teltonika Codec 8/8E devices on addr.
+func (s *Server) ListenTeltonika(addr string) error {
+	return s.listen(addr, s.serveTeltonika)
+}
+
+// ListenGT06 accepts GT06 devices on addr.
+func (s *Server) ListenGT06(addr string) error {
+	return s.listen(addr, s.serveGT06)
+}
+
+// Close stops the listeners, drops open connections and waits for their
+// sessions to finish.
+func (s *Server) Close() {
+	s.mu.Lock()
+	s.closed = true
+	for _, ln := range s.listeners {
+		ln.Close()
+	}
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.mu.Unlock()
+
+	s.wg.Wait()
+}
+
+func (s *Server) listen(addr string, serve func(conn net.Conn)) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.listeners = append(s.listeners, ln)
+	s.mu.Unlock()
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				if s.isClosed() {
+					return
+				}
+				log.Printf("Failed to accept connection on %s: %v", addr, err)
+				time.Sleep(100 * time.Millisecond)
+				continue
+			}
+
+			if !s.track(conn) {
+				conn.Close()
+				return
+			}
+			go func() {
+				defer s.untrack(conn)
+				serve(conn)
+			}()
+		}
+	}()
+
+	return nil
+}
+
+func (s *Server) isClosed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closed
+}
+
+func (s *Server) track(conn net.Conn) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return false
+	}
+	s.conns[conn] = struct{}{}
+	s.wg.Add(1)
+	return true
+}
+
+func (s *Server) untrack(conn net.Conn) {
+	conn.Close()
+	s.mu.Lock()
+	delete(s.conns, conn)
+	s.mu.Unlock()
+	s.wg.Done()
+}
+
+// extendDeadline drops a connection that stays silent for the idle timeout.
+func (s *Server) extendDeadline(conn net.Conn) {
+	if s.idleTimeout > 0 {
+		conn.SetDeadline(time.Now().Add(s.idleTimeout))
+	}
+}
+
+// handleAll passes messages to the handler in order and returns how many
+// were handled before the first failure.
+func (s *Server) handleAll(messages []*domain.LocationMessage) int {
+	for i, message := range messages {
+		if err := s.handle(message); err != nil {
+			log.Printf("Failed to process location from %s: %v", message.VehicleID, err)
+			return i
+		}
+	}
+	return len(messages)
+}
+
+func (s *Server) handle(message *domain.LocationMessage) error {
+	ctx := context.Background()
+	if s.messageTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.messageTimeout)
+		defer cancel()
+	}
+	return s.handler(ctx, message)
+}
@@ -0,0 +1,211 @@
+package gateway
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"log"
+	"net"
+
+	"github.com/fahri/go-tije/internal/domain"
+)
+
+const (
+	codec8  = 0x08
+	codec8E = 0x8E
+
+	// maxAVLLength bounds the data field so a corrupt length cannot make the
+	// gateway allocate arbitrary memory.
+	maxAVLLength = 64 * 1024
+)
+
+// Teltonika IO element IDs mapped onto telemetry.
+const (
+	ioTotalOdometer   = 16
+	ioExternalVoltage = 66
+	ioGNSSHDOP        = 182
+	ioIgnition        = 239
+)
+
+// serveTeltonika runs a Codec 8 / 8E session: the device sends its IMEI, which
+// is acknowledged with 0x01, then AVL packets, each acknowledged with the
+// number of records accepted. A short count makes the device resend the packet.
+func (s *Server) serveTeltonika(conn net.Conn) {
+	r := bufio.NewReader(conn)
+
+	s.extendDeadline(conn)
+	imei, err := readTeltonikaIMEI(r)
+	if err != nil {
+		log.Printf("Teltonika handshake from %s failed: %v", conn.RemoteAddr(), err)
+		conn.Write([]byte{0x00})
+		return
+	}
+	if _, err := conn.Write([]byte{0x01}); err != nil {
+		return
+	}
+	log.Printf("Teltonika device %s connected from %s", imei, conn.RemoteAddr())
+
+	for {
+		s.extendDeadline(conn)
+		codecID, data, err := readTeltonikaPacket(r)
+		if err == io.EOF {
+			log.Printf("Teltonika device %s disconnected", imei)
+			return
+		}
+		if err != nil {
+			log.Printf("Teltonika device %s: %v", imei, err)
+			return
+		}
+		if codecID != codec8 && codecID != codec8E {
+			log.Printf("Teltonika device %s: ignoring codec 0x%02X packet", imei, codecID)
+			continue
+		}
+
+		messages, err := decodeAVLData(imei, data)
+		if err != nil {
+			log.Printf("Teltonika device %s: %v", imei, err)
+			return
+		}
+
+		accepted := s.handleAll(messages)
+		ack := make([]byte, 4)
+		binary.BigEndian.PutUint32(ack, uint32(accepted))
+		if _, err := conn.Write(ack); err != nil {
+			return
+		}
+	}
+}
+
+func readTeltonikaIMEI(r *bufio.Reader) (string, error) {
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return "", err
+	}
+
+	length := binary.BigEndian.Uint16(header)
+	if length == 0 || length > 32 {
+		return "", fmt.Errorf("invalid IMEI length %d", length)
+	}
+
+	imei := make([]byte, length)
+	if _, err := io.ReadFull(r, imei); err != nil {
+		return "", err
+	}
+	for _, c := range imei {
+		if c < '0' || c > '9' {
+			return "", fmt.Errorf("invalid IMEI %q", imei)
+		}
+	}
+
+	return string(imei), nil
+}
+
+// readTeltonikaPacket reads one AVL packet and returns its codec ID and data
+// field (codec ID through the second record count) after checking the CRC.
+func readTeltonikaPacket(r *bufio.Reader) (byte, []byte, error) {
+	header := make([]byte, 8)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return 0, nil, err
+	}
+	if binary.BigEndian.Uint32(header[:4]) != 0 {
+		return 0, nil, fmt.Errorf("invalid packet preamble")
+	}
+
+	length := binary.BigEndian.Uint32(header[4:])
+	if length < 3 || length > maxAVLLength {
+		return 0, nil, fmt.Errorf("invalid data field length %d", length)
+	}
+
+	data := make([]byte, length+4)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return 0, nil, err
+	}
+
+	data, crc := data[:length], binary.BigEndian.Uint32(data[length:])
+	if uint32(crc16IBM(data)) != crc {
+		return 0, nil, fmt.Errorf("CRC mismatch")
+	}
+
+	return data[0], data, nil
+}
+
+// decodeAVLData decodes the records of a Codec 8 or 8E data field.
+func decodeAVLData(imei string, data []byte) ([]*domain.LocationMessage, error) {
+	r := &frameReader{b: data}
+	extended := r.u8() == codec8E
+	count := int(r.u8())
+
+	// Codec 8E widens IO element IDs and counts to two bytes.
+	width := 1
+	if extended {
+		width = 2
+	}
+
+	messages := make([]*domain.LocationMessage, 0, count)
+	for i := 0; i < count && r.err == nil; i++ {
+		message := &domain.LocationMessage{VehicleID: imei}
+		message.Timestamp = int64(r.u64() / 1000)
+		r.u8() // priority
+
+		message.Longitude = float64(int32(r.u32())) / 1e7
+		message.Latitude = float64(int32(r.u32())) / 1e7
+		altitude := float64(int16(r.u16()))
+		heading := float64(r.u16())
+		satellites := int(r.u8())
+		speed := float64(r.u16())
+		message.Altitude = &altitude
+		message.Heading = &heading
+		message.Satellites = &satellites
+		message.Speed = &speed
+
+		r.uint(width) // event IO ID
+		r.uint(width) // total IO count
+		elements := make(map[uint64]uint64)
+		for _, size := range []int{1, 2, 4, 8} {
+			n := int(r.uint(width))
+			for j := 0; j < n && r.err == nil; j++ {
+				id := r.uint(width)
+				elements[id] = r.uint(size)
+			}
+		}
+		if extended {
+			n := int(r.u16())
+			for j := 0; j < n && r.err == nil; j++ {
+				r.u16() // ID
+				r.next(int(r.u16()))
+			}
+		}
+
+		applyTeltonikaIO(message, elements)
+		messages = append(messages, message)
+	}
+
+	if trailer := int(r.u8()); r.err == nil && trailer != count {
+		return nil, fmt.Errorf("record count mismatch: %d != %d", trailer, count)
+	}
+	if r.err != nil {
+		return nil, fmt.Errorf("invalid AVL data: %v", r.err)
+	}
+
+	return messages, nil
+}
+
+func applyTeltonikaIO(message *domain.LocationMessage, elements map[uint64]uint64) {
+	if v, ok := elements[ioIgnition]; ok {
+		ignition := v == 1
+		message.Ignition = &ignition
+	}
+	if v, ok := elements[ioExternalVoltage]; ok {
+		voltage := float64(v) / 1000
+		message.BatteryVoltage = &voltage
+	}
+	if v, ok := elements[ioTotalOdometer]; ok {
+		odometer := float64(v) / 1000
+		message.Odometer = &odometer
+	}
+	if v, ok := elements[ioGNSSHDOP]; ok {
+		hdop := float64(v) / 10
+		message.HDOP = &hdop
+	}
+}
@@ -0,0 +1,351 @@
+package gateway
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"sync"
+	"testing"
+
+	"github.com/fahri/go-tije/internal/config"
+	"github.com/fahri/go-tije/internal/domain"
+)
+
+// Sample packets from the Teltonika Codec 8 and Codec 8E documentation.
+const (
+	teltonikaIMEI = "000F 333536333037303432343431303133"
+
+	codec8Sample = "00000000 00000036 08 01" +
+		" 0000016B40D8EA30 01 00000000 00000000 0000 0000 00 0000" +
+		" 01 05 02 15 03 01 01 01 42 5E0F 01 F1 0000601A 01 4E 0000000000000000" +
+		" 01 0000C7CF"
+
+	codec8ESample = "00000000 0000004A 8E 01" +
+		" 0000016B412CEE00 01 00000000 00000000 0000 0000 00 0000" +
+		" 0001 0005 0001 0001 01 0001 0011 001D 0001 0010 015E2C88" +
+		" 0002 000B 000000003544C87A 000E 000000001DD7E06A 0000" +
+		" 01 00002994"
+)
+
+// teltonikaPacket wraps an AVL data field in the preamble, length and CRC.
+func teltonikaPacket(data []byte) []byte {
+	b := make([]byte, 8, len(data)+12)
+	binary.BigEndian.PutUint32(b[4:], uint32(len(data)))
+	b = append(b, data...)
+	return binary.BigEndian.AppendUint32(b, uint32(crc16IBM(data)))
+}
+
+func readPacket(b []byte) (byte, []byte, error) {
+	return readTeltonikaPacket(bufio.NewReader(bytes.NewReader(b)))
+}
+
+func TestReadTeltonikaIMEI(t *testing.T) {
+	tests := []struct {
+		name    string
+		frame   string
+		want    string
+		wantErr bool
+	}{
+		{"sample", teltonikaIMEI, "356307042441013", false},
+		{"zero length", "0000", "", true},
+		{"too long", "0021", "", true},
+		{"not digits", "0003 3341 33", "", true},
+		{"truncated", "000F 3335363330", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := readTeltonikaIMEI(bufio.NewReader(bytes.NewReader(decodeHex(t, tt.frame))))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("IMEI = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadTeltonikaPacketCRC(t *testing.T) {
+	for name, sample := range map[string]string{"codec 8": codec8Sample, "codec 8E": codec8ESample} {
+		t.Run(name, func(t *testing.T) {
+			frame := decodeHex(t, sample)
+			if built := teltonikaPacket(frame[8 : len(frame)-4]); !bytes.Equal(built, frame) {
+				t.Fatalf("CRC of the sample data field does not match the vendor's:\n got %X\nwant %X", built, frame)
+			}
+
+			codecID, data, err := readPacket(frame)
+			if err != nil {
+				t.Fatalf("readTeltonikaPacket: %v", err)
+			}
+			if codecID != frame[8] || len(data) != len(frame)-12 {
+				t.Errorf("codec 0x%02X with %d data bytes, want 0x%02X with %d", codecID, len(data), frame[8], len(frame)-12)
+			}
+
+			corrupt := append([]byte(nil), frame...)
+			corrupt[20] ^= 0x01
+			if _, _, err := readPacket(corrupt); err == nil || err.Error() != "CRC mismatch" {
+				t.Errorf("corrupted record: err = %v, want CRC mismatch", err)
+			}
+			badCRC := append([]byte(nil), frame...)
+			badCRC[len(badCRC)-1] ^= 0xFF
+			if _, _, err := readPacket(badCRC); err == nil || err.Error() != "CRC mismatch" {
+				t.Errorf("corrupted CRC: err = %v, want CRC mismatch", err)
+			}
+		})
+	}
+}
+
+func TestReadTeltonikaPacketInvalid(t *testing.T) {
+	frame := decodeHex(t, codec8Sample)
+	tests := []struct {
+		name  string
+		frame []byte
+		want  error
+	}{
+		{"empty", nil, io.EOF},
+		{"truncated header", frame[:6], io.ErrUnexpectedEOF},
+		{"truncated data", frame[:40], io.ErrUnexpectedEOF},
+		{"missing CRC", frame[:len(frame)-2], io.ErrUnexpectedEOF},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := readPacket(tt.frame); err != tt.want {
+				t.Errorf("err = %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	preamble := append([]byte(nil), frame...)
+	preamble[0] = 0x01
+	if _, _, err := readPacket(preamble); err == nil {
+		t.Error("accepted a packet with a non-zero preamble")
+	}
+	huge := append([]byte(nil), frame...)
+	binary.BigEndian.PutUint32(huge[4:], maxAVLLength+1)
+	if _, _, err := readPacket(huge); err == nil {
+		t.Error("accepted a data field longer than maxAVLLength")
+	}
+}
+
+func TestDecodeAVLData(t *testing.T) {
+	codec8 := decodeHex(t, codec8Sample)
+	messages, err := decodeAVLData("356307042441013", codec8[8:len(codec8)-4])
+	if err != nil {
+		t.Fatalf("codec 8: %v", err)
+	}
+	if len(messages) != 1 {
+		t.Fatalf("codec 8: got %d records, want 1", len(messages))
+	}
+	m := messages[0]
+	if m.VehicleID != "356307042441013" || m.Timestamp != 1560161086 {
+		t.Errorf("codec 8: vehicle %q at %d", m.VehicleID, m.Timestamp)
+	}
+	if m.BatteryVoltage == nil || *m.BatteryVoltage != 24.079 {
+		t.Errorf("codec 8: battery voltage %v, want 24.079", m.BatteryVoltage)
+	}
+	if m.Odometer != nil || m.Ignition != nil {
+		t.Errorf("codec 8: telemetry the sample does not carry was set")
+	}
+
+	codec8E := decodeHex(t, codec8ESample)
+	messages, err = decodeAVLData("356307042441013", codec8E[8:len(codec8E)-4])
+	if err != nil {
+		t.Fatalf("codec 8E: %v", err)
+	}
+	if len(messages) != 1 || messages[0].Timestamp != 1560166592 {
+		t.Fatalf("codec 8E: got %d records", len(messages))
+	}
+	if m := messages[0]; m.Odometer == nil || *m.Odometer != 22949 {
+		t.Errorf("codec 8E: odometer %v, want 22949", m.Odometer)
+	}
+}
+
+func TestDecodeAVLDataLocation(t *testing.T) {
+	// Two records: Jakarta with ignition on and HDOP, then a point south and
+	// west of zero to check the signed coordinates.
+	data := decodeHex(t, "08 02"+
+		" 0000018F4E01EC60 00 3FB00380 FC4DF440 0023 010E 0A 0032"+
+		" EF 02 01 EF 01 01 B6 0007 00 00"+
+		" 0000018F4E026190 00 F8D8F200 FF676980 0000 0000 04 0000"+
+		" 00 00 00 00 00 00"+
+		" 02")
+
+	messages, err := decodeAVLData("352094080000001", data)
+	if err != nil {
+		t.Fatalf("decodeAVLData: %v", err)
+	}
+	if len(messages) != 2 {
+		t.Fatalf("got %d records, want 2", len(messages))
+	}
+
+	m := messages[0]
+	if m.Timestamp != 1715000700 || m.Latitude != -6.2000064 || m.Longitude != 106.8499840 {
+		t.Errorf("record 1 at %d: %v, %v", m.Timestamp, m.Latitude, m.Longitude)
+	}
+	if *m.Altitude != 35 || *m.Heading != 270 || *m.Satellites != 10 || *m.Speed != 50 {
+		t.Errorf("record 1 telemetry: altitude %v heading %v satellites %v speed %v", *m.Altitude, *m.Heading, *m.Satellites, *m.Speed)
+	}
+	if m.Ignition == nil || !*m.Ignition || m.HDOP == nil || *m.HDOP != 0.7 {
+		t.Errorf("record 1 IO: ignition %v, hdop %v", m.Ignition, m.HDOP)
+	}
+
+	m = messages[1]
+	if m.Latitude != -1 || m.Longitude != -12 {
+		t.Errorf("record 2 at %v, %v", m.Latitude, m.Longitude)
+	}
+	if m.Ignition != nil {
+		t.Errorf("record 2 has ignition %v without the IO element", *m.Ignition)
+	}
+}
+
+func TestDecodeAVLDataVariableLengthIO(t *testing.T) {
+	// The Codec 8E sample with two variable-length elements appended: a
+	// 3-byte and an empty one. They are skipped without losing the trailer.
+	sample := decodeHex(t, codec8ESample)
+	data := append([]byte(nil), sample[8:len(sample)-7]...)
+	data = append(data, decodeHex(t, "0002 0101 0003 AABBCC 0102 0000 01")...)
+
+	messages, err := decodeAVLData("356307042441013", data)
+	if err != nil {
+		t.Fatalf("decodeAVLData: %v", err)
+	}
+	if len(messages) != 1 || messages[0].Odometer == nil || *messages[0].Odometer != 22949 {
+		t.Errorf("records %+v after variable-length IO", messages)
+	}
+
+	// A variable-length element claiming more bytes than the packet holds.
+	short := append([]byte(nil), sample[8:len(sample)-7]...)
+	short = append(short, decodeHex(t, "0001 0101 0010 AABBCC 01")...)
+	if _, err := decodeAVLData("356307042441013", short); err == nil {
+		t.Error("accepted a variable-length element longer than the packet")
+	}
+}
+
+func TestDecodeAVLDataInvalid(t *testing.T) {
+	sample := decodeHex(t, codec8Sample)
+	data := sample[8 : len(sample)-4]
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"empty", nil},
+		{"codec only", data[:1]},
+		{"truncated record", data[:30]},
+		{"missing trailer count", data[:len(data)-1]},
+		{"trailer count mismatch", append(append([]byte(nil), data[:len(data)-1]...), 0x02)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if messages, err := decodeAVLData("356307042441013", tt.data); err == nil {
+				t.Errorf("decoded %d records from an invalid data field", len(messages))
+			}
+		})
+	}
+}
+
+// recorder is a Handler that keeps the locations it is given and fails once
+// it holds limit of them.
+type recorder struct {
+	mu       sync.Mutex
+	limit    int
+	messages []*domain.LocationMessage
+}
+
+func (r *recorder) handle(ctx context.Context, message *domain.LocationMessage) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.limit > 0 && len(r.messages) >= r.limit {
+		return context.DeadlineExceeded
+	}
+	r.messages = append(r.messages, message)
+	return nil
+}
+
+func (r *recorder) received() []*domain.LocationMessage {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.messages
+}
+
+// session runs serve on one end of a pipe and returns the other end. The
+// returned function closes the device side and waits for serve to return.
+func session(t *testing.T, serve func(*Server, net.Conn), handler Handler) (net.Conn, func()) {
+	t.Helper()
+	device, gateway := net.Pipe()
+	s := NewServer(&config.GatewayConfig{}, handler)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		defer gateway.Close()
+		serve(s, gateway)
+	}()
+	return device, func() {
+		device.Close()
+		<-done
+	}
+}
+
+func exchange(t *testing.T, conn net.Conn, frame []byte, reply int) []byte {
+	t.Helper()
+	if _, err := conn.Write(frame); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	b := make([]byte, reply)
+	if _, err := io.ReadFull(conn, b); err != nil {
+		t.Fatalf("read reply: %v", err)
+	}
+	return b
+}
+
+func TestServeTeltonika(t *testing.T) {
+	rec := &recorder{}
+	conn, done := session(t, (*Server).serveTeltonika, rec.handle)
+	defer done()
+
+	if ack := exchange(t, conn, decodeHex(t, teltonikaIMEI), 1); ack[0] != 0x01 {
+		t.Fatalf("IMEI ACK = %X, want 01", ack)
+	}
+	if ack := exchange(t, conn, decodeHex(t, codec8Sample), 4); !bytes.Equal(ack, []byte{0, 0, 0, 1}) {
+		t.Errorf("codec 8 ACK = %X, want 00000001", ack)
+	}
+	if ack := exchange(t, conn, decodeHex(t, codec8ESample), 4); !bytes.Equal(ack, []byte{0, 0, 0, 1}) {
+		t.Errorf("codec 8E ACK = %X, want 00000001", ack)
+	}
+
+	messages := rec.received()
+	if len(messages) != 2 || messages[0].VehicleID != "356307042441013" {
+		t.Errorf("handled %d locations", len(messages))
+	}
+}
+
+func TestServeTeltonikaPartialACK(t *testing.T) {
+	// The handler takes one of two records; the ACK tells the device to
+	// resend the packet.
+	data := decodeHex(t, "08 02"+
+		" 0000018F4E01EC60 00 3FB00380 FC4DF440 0023 010E 0A 0032 00 00 00 00 00 00"+
+		" 0000018F4E026190 00 3FB00380 FC4DF440 0023 010E 0A 0032 00 00 00 00 00 00"+
+		" 02")
+	rec := &recorder{limit: 1}
+	conn, done := session(t, (*Server).serveTeltonika, rec.handle)
+	defer done()
+
+	exchange(t, conn, decodeHex(t, teltonikaIMEI), 1)
+	if ack := exchange(t, conn, teltonikaPacket(data), 4); !bytes.Equal(ack, []byte{0, 0, 0, 1}) {
+		t.Errorf("ACK = %X, want 00000001", ack)
+	}
+}
+
+func TestServeTeltonikaRejectsIMEI(t *testing.T) {
+	conn, done := session(t, (*Server).serveTeltonika, (&recorder{}).handle)
+	defer done()
+
+	if ack := exchange(t, conn, decodeHex(t, "0003 3341 33"), 1); ack[0] != 0x00 {
+		t.Errorf("IMEI ACK = %X, want 00", ack)
+	}
+}
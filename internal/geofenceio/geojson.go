@@ -0,0 +1,188 @@
+package geofenceio
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/fahri/go-tije/internal/domain"
+	"github.com/fahri/go-tije/pkg/geofence"
+)
+
+type featureCollection struct {
+	Type     string     `json:"type"`
+	Features []*feature `json:"features"`
+}
+
+type feature struct {
+	Type       string                 `json:"type"`
+	ID         interface{}            `json:"id,omitempty"`
+	Geometry   json.RawMessage        `json:"geometry"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+// DecodeGeoJSON reads a FeatureCollection (or a single Feature). Point
+// features become circles and need a "radius" property; Polygon and
+// MultiPolygon features become polygons; LineString features become
+// corridors with "radius" as the buffer width.
+func DecodeGeoJSON(data []byte) ([]*domain.Geofence, error) {
+	var header struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(data, &header); err != nil {
+		return nil, err
+	}
+
+	var features []*feature
+	switch header.Type {
+	case "FeatureCollection":
+		var collection featureCollection
+		if err := json.Unmarshal(data, &collection); err != nil {
+			return nil, err
+		}
+		features = collection.Features
+	case "Feature":
+		var single feature
+		if err := json.Unmarshal(data, &single); err != nil {
+			return nil, err
+		}
+		features = []*feature{&single}
+	default:
+		return nil, fmt.Errorf("expected a FeatureCollection or Feature, got %q", header.Type)
+	}
+
+	geofences := make([]*domain.Geofence, 0, len(features))
+	for i, f := range features {
+		zone, err := decodeFeature(f)
+		if err != nil {
+			return nil, fmt.Errorf("feature %d: %v", i, err)
+		}
+		geofences = append(geofences, zone)
+	}
+
+	return geofences, nil
+}
+
+func decodeFeature(f *feature) (*domain.Geofence, error) {
+	var geometry struct {
+		Type        string          `json:"type"`
+		Coordinates json.RawMessage `json:"coordinates"`
+	}
+	if err := json.Unmarshal(f.Geometry, &geometry); err != nil {
+		return nil, fmt.Errorf("invalid geometry: %v", err)
+	}
+
+	zone, err := newGeofence(func(key string) (interface{}, bool) {
+		return lookup(f.Properties, key)
+	})
+	if err != nil {
+		return nil, err
+	}
+	// The Feature id, which the export writes, wins over an "id" property.
+	if f.ID != nil {
+		zone.ID = strings.TrimSpace(toString(f.ID))
+	}
+
+	switch geometry.Type {
+	case "Point":
+		var coords []float64
+		if err := json.Unmarshal(geometry.Coordinates, &coords); err != nil {
+			return nil, err
+		}
+		if len(coords) < 2 {
+			return nil, fmt.Errorf("invalid point coordinates")
+		}
+		zone.Type = domain.GeofenceTypeCircle
+		zone.Longitude = coords[0]
+		zone.Latitude = coords[1]
+	case "LineString":
+		var line geofence.Polyline
+		if err := json.Unmarshal(geometry.Coordinates, &line); err != nil {
+			return nil, err
+		}
+		zone.Type = domain.GeofenceTypeCorridor
+		zone.Line = line
+	case "Polygon", "MultiPolygon":
+		polygon, err := geofence.ParseGeoJSONGeometry(f.Geometry)
+		if err != nil {
+			return nil, err
+		}
+		zone.Type = domain.GeofenceTypePolygon
+		zone.Polygon = polygon
+	default:
+		return nil, fmt.Errorf("unsupported geometry type: %s", geometry.Type)
+	}
+
+	return zone, nil
+}
+
+// EncodeGeoJSON writes the zones as a FeatureCollection.
+func EncodeGeoJSON(geofences []*domain.Geofence) ([]byte, error) {
+	collection := featureCollection{
+		Type:     "FeatureCollection",
+		Features: make([]*feature, 0, len(geofences)),
+	}
+
+	for _, zone := range geofences {
+		var geometry interface{}
+		properties := map[string]interface{}{
+			"name":                 zone.Name,
+			"tags":                 nonNil(zone.Tags),
+			"vehicle_ids":          nonNil(zone.VehicleIDs),
+			"group_ids":            nonNil(zone.GroupIDs),
+			"dwell_seconds":        zone.DwellSeconds,
+			"hysteresis_meters":    zone.HysteresisMeters,
+			"min_points":           zone.MinPoints,
+			"min_duration_seconds": zone.MinDurationSeconds,
+			"deviation_seconds":    zone.DeviationSeconds,
+			"active":               zone.Active,
+		}
+
+		if zone.Schedule != nil {
+			properties["schedule"] = zone.Schedule
+		}
+
+		switch zone.Type {
+		case domain.GeofenceTypePolygon:
+			geometry = map[string]interface{}{
+				"type":        "MultiPolygon",
+				"coordinates": zone.Polygon,
+			}
+		case domain.GeofenceTypeCorridor:
+			geometry = map[string]interface{}{
+				"type":        "LineString",
+				"coordinates": zone.Line,
+			}
+			properties["radius"] = zone.Radius
+		default:
+			geometry = map[string]interface{}{
+				"type":        "Point",
+				"coordinates": []float64{zone.Longitude, zone.Latitude},
+			}
+			properties["radius"] = zone.Radius
+		}
+
+		raw, err := json.Marshal(geometry)
+		if err != nil {
+			return nil, err
+		}
+
+		collection.Features = append(collection.Features, &feature{
+			Type:       "Feature",
+			ID:         zone.ID,
+			Geometry:   raw,
+			Properties: properties,
+		})
+	}
+
+	return json.Marshal(collection)
+}
+
+func lookup(properties map[string]interface{}, key string) (interface{}, bool) {
+	for k, v := range properties {
+		if strings.EqualFold(k, key) {
+			return v, true
+		}
+	}
+	return nil, false
+}
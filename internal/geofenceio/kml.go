@@ -0,0 +1,316 @@
+package geofenceio
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/fahri/go-tije/internal/domain"
+	"github.com/fahri/go-tije/pkg/geofence"
+)
+
+const kmlNamespace = "http://www.opengis.net/kml/2.2"
+
+type kmlDocument struct {
+	XMLName   xml.Name `xml:"kml"`
+	Namespace string   `xml:"xmlns,attr"`
+	Document  struct {
+		Name       string          `xml:"name"`
+		Placemarks []*kmlPlacemark `xml:"Placemark"`
+	} `xml:"Document"`
+}
+
+type kmlPlacemark struct {
+	ID            string            `xml:"id,attr,omitempty"`
+	Name          string            `xml:"name"`
+	ExtendedData  *kmlExtendedData  `xml:"ExtendedData,omitempty"`
+	Point         *kmlPoint         `xml:"Point,omitempty"`
+	LineString    *kmlLineString    `xml:"LineString,omitempty"`
+	Polygon       *kmlPolygon       `xml:"Polygon,omitempty"`
+	MultiGeometry *kmlMultiGeometry `xml:"MultiGeometry,omitempty"`
+}
+
+type kmlExtendedData struct {
+	Data []kmlData `xml:"Data"`
+	// SchemaData is what QGIS writes for attribute columns.
+	SchemaData []struct {
+		SimpleData []kmlSimpleData `xml:"SimpleData"`
+	} `xml:"SchemaData,omitempty"`
+}
+
+type kmlData struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value"`
+}
+
+type kmlSimpleData struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:",chardata"`
+}
+
+type kmlPoint struct {
+	Coordinates string `xml:"coordinates"`
+}
+
+type kmlLineString struct {
+	Coordinates string `xml:"coordinates"`
+}
+
+type kmlPolygon struct {
+	Outer kmlBoundary   `xml:"outerBoundaryIs"`
+	Inner []kmlBoundary `xml:"innerBoundaryIs"`
+}
+
+type kmlBoundary struct {
+	LinearRing struct {
+		Coordinates string `xml:"coordinates"`
+	} `xml:"LinearRing"`
+}
+
+type kmlMultiGeometry struct {
+	Polygons []*kmlPolygon `xml:"Polygon"`
+}
+
+// DecodeKML reads every Placemark in the document, at any folder depth.
+// Points become circles and need a "radius" ExtendedData entry; Polygons
+// and MultiGeometry polygons become polygons; LineStrings become corridors
+// with "radius" as the buffer width.
+func DecodeKML(data []byte) ([]*domain.Geofence, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+
+	var geofences []*domain.Geofence
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		start, ok := token.(xml.StartElement)
+		if !ok || start.Name.Local != "Placemark" {
+			continue
+		}
+
+		var placemark kmlPlacemark
+		if err := decoder.DecodeElement(&placemark, &start); err != nil {
+			return nil, err
+		}
+
+		zone, err := decodePlacemark(&placemark)
+		if err != nil {
+			return nil, fmt.Errorf("placemark %d (%s): %v", len(geofences), placemark.Name, err)
+		}
+		geofences = append(geofences, zone)
+	}
+
+	return geofences, nil
+}
+
+func decodePlacemark(placemark *kmlPlacemark) (*domain.Geofence, error) {
+	properties := map[string]interface{}{}
+	if placemark.ExtendedData != nil {
+		for _, data := range placemark.ExtendedData.Data {
+			properties[data.Name] = strings.TrimSpace(data.Value)
+		}
+		for _, schema := range placemark.ExtendedData.SchemaData {
+			for _, data := range schema.SimpleData {
+				properties[data.Name] = strings.TrimSpace(data.Value)
+			}
+		}
+	}
+	if placemark.Name != "" {
+		properties["name"] = placemark.Name
+	}
+	if placemark.ID != "" {
+		properties["id"] = placemark.ID
+	}
+
+	zone, err := newGeofence(func(key string) (interface{}, bool) {
+		return lookup(properties, key)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case placemark.Point != nil:
+		ring, err := parseCoordinates(placemark.Point.Coordinates)
+		if err != nil {
+			return nil, err
+		}
+		if len(ring) != 1 {
+			return nil, fmt.Errorf("point must have exactly one coordinate")
+		}
+		zone.Type = domain.GeofenceTypeCircle
+		zone.Latitude = ring[0].Latitude
+		zone.Longitude = ring[0].Longitude
+	case placemark.LineString != nil:
+		line, err := parseCoordinates(placemark.LineString.Coordinates)
+		if err != nil {
+			return nil, err
+		}
+		zone.Type = domain.GeofenceTypeCorridor
+		zone.Line = geofence.Polyline(line)
+	case placemark.Polygon != nil:
+		polygon, err := placemark.Polygon.toPolygon()
+		if err != nil {
+			return nil, err
+		}
+		zone.Type = domain.GeofenceTypePolygon
+		zone.Polygon = geofence.MultiPolygon{polygon}
+	case placemark.MultiGeometry != nil && len(placemark.MultiGeometry.Polygons) > 0:
+		zone.Type = domain.GeofenceTypePolygon
+		for _, p := range placemark.MultiGeometry.Polygons {
+			polygon, err := p.toPolygon()
+			if err != nil {
+				return nil, err
+			}
+			zone.Polygon = append(zone.Polygon, polygon)
+		}
+	default:
+		return nil, fmt.Errorf("placemark has no supported geometry")
+	}
+
+	return zone, nil
+}
+
+func (p *kmlPolygon) toPolygon() (geofence.Polygon, error) {
+	outer, err := parseCoordinates(p.Outer.LinearRing.Coordinates)
+	if err != nil {
+		return geofence.Polygon{}, err
+	}
+
+	polygon := geofence.Polygon{Outer: outer}
+	for _, boundary := range p.Inner {
+		hole, err := parseCoordinates(boundary.LinearRing.Coordinates)
+		if err != nil {
+			return geofence.Polygon{}, err
+		}
+		polygon.Holes = append(polygon.Holes, hole)
+	}
+
+	return polygon, nil
+}
+
+// parseCoordinates reads whitespace separated "lon,lat[,alt]" tuples.
+func parseCoordinates(s string) (geofence.Ring, error) {
+	var ring geofence.Ring
+	for _, tuple := range strings.Fields(s) {
+		parts := strings.Split(tuple, ",")
+		if len(parts) < 2 {
+			return nil, fmt.Errorf("invalid coordinate %q", tuple)
+		}
+
+		lon, err := strconv.ParseFloat(parts[0], 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid longitude %q", parts[0])
+		}
+		lat, err := strconv.ParseFloat(parts[1], 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid latitude %q", parts[1])
+		}
+
+		ring = append(ring, geofence.Point{Latitude: lat, Longitude: lon})
+	}
+
+	return ring, nil
+}
+
+// EncodeKML writes the zones as Placemarks. Circles are exported as Points
+// and corridors as LineStrings, with their radius in ExtendedData so they
+// round-trip through DecodeKML.
+func EncodeKML(geofences []*domain.Geofence) ([]byte, error) {
+	var doc kmlDocument
+	doc.Namespace = kmlNamespace
+	doc.Document.Name = "geofences"
+
+	for _, zone := range geofences {
+		placemark := &kmlPlacemark{
+			Name: zone.Name,
+			ExtendedData: &kmlExtendedData{
+				Data: []kmlData{
+					{Name: "id", Value: zone.ID},
+					{Name: "tags", Value: strings.Join(zone.Tags, ",")},
+					{Name: "vehicle_ids", Value: strings.Join(zone.VehicleIDs, ",")},
+					{Name: "group_ids", Value: strings.Join(zone.GroupIDs, ",")},
+					{Name: "dwell_seconds", Value: strconv.Itoa(zone.DwellSeconds)},
+					{Name: "hysteresis_meters", Value: strconv.FormatFloat(zone.HysteresisMeters, 'f', -1, 64)},
+					{Name: "min_points", Value: strconv.Itoa(zone.MinPoints)},
+					{Name: "min_duration_seconds", Value: strconv.Itoa(zone.MinDurationSeconds)},
+					{Name: "deviation_seconds", Value: strconv.Itoa(zone.DeviationSeconds)},
+					{Name: "active", Value: strconv.FormatBool(zone.Active)},
+				},
+			},
+		}
+
+		if zone.Schedule != nil {
+			schedule, err := json.Marshal(zone.Schedule)
+			if err != nil {
+				return nil, err
+			}
+			placemark.ExtendedData.Data = append(placemark.ExtendedData.Data, kmlData{
+				Name:  "schedule",
+				Value: string(schedule),
+			})
+		}
+
+		switch zone.Type {
+		case domain.GeofenceTypePolygon:
+			multi := &kmlMultiGeometry{}
+			for _, polygon := range zone.Polygon {
+				multi.Polygons = append(multi.Polygons, toKMLPolygon(polygon))
+			}
+			placemark.MultiGeometry = multi
+		case domain.GeofenceTypeCorridor:
+			placemark.LineString = &kmlLineString{
+				Coordinates: formatCoordinates(geofence.Ring(zone.Line)),
+			}
+			placemark.ExtendedData.Data = append(placemark.ExtendedData.Data, kmlData{
+				Name:  "radius",
+				Value: strconv.FormatFloat(zone.Radius, 'f', -1, 64),
+			})
+		default:
+			placemark.Point = &kmlPoint{
+				Coordinates: formatCoordinates(geofence.Ring{{Latitude: zone.Latitude, Longitude: zone.Longitude}}),
+			}
+			placemark.ExtendedData.Data = append(placemark.ExtendedData.Data, kmlData{
+				Name:  "radius",
+				Value: strconv.FormatFloat(zone.Radius, 'f', -1, 64),
+			})
+		}
+
+		doc.Document.Placemarks = append(doc.Document.Placemarks, placemark)
+	}
+
+	data, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]byte(xml.Header), data...), nil
+}
+
+func toKMLPolygon(polygon geofence.Polygon) *kmlPolygon {
+	p := &kmlPolygon{}
+	p.Outer.LinearRing.Coordinates = formatCoordinates(polygon.Outer)
+	for _, hole := range polygon.Holes {
+		var boundary kmlBoundary
+		boundary.LinearRing.Coordinates = formatCoordinates(hole)
+		p.Inner = append(p.Inner, boundary)
+	}
+	return p
+}
+
+func formatCoordinates(ring geofence.Ring) string {
+	tuples := make([]string, len(ring))
+	for i, p := range ring {
+		tuples[i] = strconv.FormatFloat(p.Longitude, 'f', -1, 64) + "," + strconv.FormatFloat(p.Latitude, 'f', -1, 64)
+	}
+	return strings.Join(tuples, " ")
+}
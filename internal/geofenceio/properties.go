@@ -0,0 +1,142 @@
+package geofenceio
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/fahri/go-tije/internal/domain"
+)
+
+// newGeofence maps the well-known properties (id, name, radius, tags,
+// vehicle_ids, group_ids, dwell_seconds, hysteresis and deviation settings,
+// schedule, active) onto a zone. Unknown properties are ignored; a schedule
+// that cannot be read is an error rather than silently dropped.
+func newGeofence(property func(key string) (interface{}, bool)) (*domain.Geofence, error) {
+	zone := &domain.Geofence{Active: true}
+
+	if v, ok := property("id"); ok {
+		zone.ID = strings.TrimSpace(toString(v))
+	}
+	if v, ok := property("name"); ok {
+		zone.Name = strings.TrimSpace(toString(v))
+	}
+	if v, ok := property("radius"); ok {
+		zone.Radius, _ = toFloat(v)
+	}
+	if v, ok := property("dwell_seconds"); ok {
+		dwell, _ := toFloat(v)
+		zone.DwellSeconds = int(dwell)
+	}
+	if v, ok := property("hysteresis_meters"); ok {
+		zone.HysteresisMeters, _ = toFloat(v)
+	}
+	if v, ok := property("min_points"); ok {
+		points, _ := toFloat(v)
+		zone.MinPoints = int(points)
+	}
+	if v, ok := property("min_duration_seconds"); ok {
+		duration, _ := toFloat(v)
+		zone.MinDurationSeconds = int(duration)
+	}
+	if v, ok := property("deviation_seconds"); ok {
+		deviation, _ := toFloat(v)
+		zone.DeviationSeconds = int(deviation)
+	}
+	if v, ok := property("schedule"); ok {
+		schedule, err := toSchedule(v)
+		if err != nil {
+			return nil, err
+		}
+		zone.Schedule = schedule
+	}
+	if v, ok := property("active"); ok {
+		if active, err := strconv.ParseBool(toString(v)); err == nil {
+			zone.Active = active
+		}
+	}
+	if v, ok := property("tags"); ok {
+		zone.Tags = toList(v)
+	}
+	if v, ok := property("vehicle_ids"); ok {
+		zone.VehicleIDs = toList(v)
+	}
+	if v, ok := property("group_ids"); ok {
+		zone.GroupIDs = toList(v)
+	}
+
+	return zone, nil
+}
+
+func toString(v interface{}) string {
+	switch value := v.(type) {
+	case string:
+		return value
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(value)
+	default:
+		return ""
+	}
+}
+
+func toFloat(v interface{}) (float64, error) {
+	if value, ok := v.(float64); ok {
+		return value, nil
+	}
+	return strconv.ParseFloat(strings.TrimSpace(toString(v)), 64)
+}
+
+// toList accepts either a JSON array or a comma separated string.
+func toList(v interface{}) []string {
+	var raw []string
+	switch value := v.(type) {
+	case []interface{}:
+		for _, item := range value {
+			raw = append(raw, toString(item))
+		}
+	default:
+		raw = strings.Split(toString(v), ",")
+	}
+
+	var list []string
+	for _, item := range raw {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// toSchedule accepts a JSON object or, as KML ExtendedData stores it, a JSON
+// encoded string. A null schedule means the zone is always active.
+func toSchedule(v interface{}) (*domain.GeofenceSchedule, error) {
+	if v == nil {
+		return nil, nil
+	}
+
+	var data []byte
+	if value, ok := v.(string); ok {
+		data = []byte(value)
+	} else {
+		data, _ = json.Marshal(v)
+	}
+
+	var schedule domain.GeofenceSchedule
+	if err := json.Unmarshal(data, &schedule); err != nil {
+		return nil, fmt.Errorf("invalid schedule: %v", err)
+	}
+	if err := schedule.Validate(); err != nil {
+		return nil, err
+	}
+	return &schedule, nil
+}
+
+func nonNil(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
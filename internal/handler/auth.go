@@ -0,0 +1,33 @@
+package handler
+
+import (
+	"crypto/subtle"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// AdminAuth guards administrative endpoints with a static token, sent as
+// "Authorization: Bearer <token>" or "X-API-Key".
+func AdminAuth(token string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if subtle.ConstantTimeCompare([]byte(requestToken(c)), []byte(token)) != 1 {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "missing or invalid admin token",
+			})
+		}
+		return c.Next()
+	}
+}
+
+// requestToken returns the token from the X-API-Key header or a bearer
+// Authorization header.
+func requestToken(c *fiber.Ctx) string {
+	if token := c.Get("X-API-Key"); token != "" {
+		return token
+	}
+	if auth := c.Get(fiber.HeaderAuthorization); strings.HasPrefix(auth, "Bearer ") {
+		return strings.TrimSpace(strings.TrimPrefix(auth, "Bearer "))
+	}
+	return ""
+}
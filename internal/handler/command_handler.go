@@ -0,0 +1,106 @@
+package handler
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/fahri/go-tije/internal/domain"
+	"github.com/fahri/go-tije/internal/service"
+	"github.com/gofiber/fiber/v2"
+)
+
+type CommandHandler struct {
+	service    service.CommandService
+	maxTimeout time.Duration
+}
+
+func NewCommandHandler(service service.CommandService, maxTimeout time.Duration) *CommandHandler {
+	return &CommandHandler{
+		service:    service,
+		maxTimeout: maxTimeout,
+	}
+}
+
+type commandRequest struct {
+	Type           string               `json:"type"`
+	Params         domain.CommandParams `json:"params"`
+	TimeoutSeconds int                  `json:"timeout_seconds"`
+}
+
+// Send publishes a command to the vehicle and returns it as pending; poll Get
+// for the outcome.
+func (h *CommandHandler) Send(c *fiber.Ctx) error {
+	var req commandRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid request body",
+		})
+	}
+
+	timeout := time.Duration(req.TimeoutSeconds) * time.Second
+	if timeout < 0 || timeout > h.maxTimeout {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": fmt.Sprintf("timeout_seconds must be at most %d", int(h.maxTimeout.Seconds())),
+		})
+	}
+
+	command := &domain.VehicleCommand{
+		VehicleID: c.Params("vehicle_id"),
+		Type:      req.Type,
+		Params:    req.Params,
+	}
+	if err := command.Validate(); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	if err := h.service.Send(c.Context(), command, timeout); err != nil {
+		if command.Status == domain.CommandStatusFailed {
+			return c.Status(fiber.StatusBadGateway).JSON(command)
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to send command",
+		})
+	}
+
+	return c.Status(fiber.StatusAccepted).JSON(command)
+}
+
+func (h *CommandHandler) List(c *fiber.Ctx) error {
+	limit := c.QueryInt("limit", 50)
+	if limit <= 0 || limit > 500 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "limit must be between 1 and 500",
+		})
+	}
+
+	commands, err := h.service.List(c.Context(), c.Params("vehicle_id"), limit)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to list commands",
+		})
+	}
+
+	if commands == nil {
+		commands = []*domain.VehicleCommand{}
+	}
+
+	return c.JSON(commands)
+}
+
+func (h *CommandHandler) Get(c *fiber.Ctx) error {
+	command, err := h.service.Get(c.Context(), c.Params("vehicle_id"), c.Params("id"))
+	if err != nil {
+		if err.Error() == "command not found" {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to get command",
+		})
+	}
+
+	return c.JSON(command)
+}
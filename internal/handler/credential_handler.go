@@ -0,0 +1,89 @@
+package handler
+
+import (
+	"github.com/fahri/go-tije/internal/domain"
+	"github.com/fahri/go-tije/internal/service"
+	"github.com/gofiber/fiber/v2"
+)
+
+type CredentialHandler struct {
+	service service.CredentialService
+}
+
+func NewCredentialHandler(service service.CredentialService) *CredentialHandler {
+	return &CredentialHandler{
+		service: service,
+	}
+}
+
+type credentialRequest struct {
+	Name      string `json:"name"`
+	Type      string `json:"type"`
+	VehicleID string `json:"vehicle_id"`
+	GroupID   string `json:"group_id"`
+}
+
+func (h *CredentialHandler) List(c *fiber.Ctx) error {
+	credentials, err := h.service.List(c.Context())
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to list credentials",
+		})
+	}
+
+	if credentials == nil {
+		credentials = []*domain.IngestCredential{}
+	}
+
+	return c.JSON(credentials)
+}
+
+// Create returns the new credential with its token, which is only shown once.
+func (h *CredentialHandler) Create(c *fiber.Ctx) error {
+	var req credentialRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid request body",
+		})
+	}
+
+	credential := &domain.IngestCredential{
+		Name:      req.Name,
+		Type:      req.Type,
+		VehicleID: req.VehicleID,
+		GroupID:   req.GroupID,
+	}
+	if err := credential.Validate(); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	if err := h.service.Create(c.Context(), credential); err != nil {
+		if err.Error() == "group not found" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to create credential",
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(credential)
+}
+
+func (h *CredentialHandler) Delete(c *fiber.Ctx) error {
+	if err := h.service.Delete(c.Context(), c.Params("id")); err != nil {
+		if err.Error() == "credential not found" {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to delete credential",
+		})
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
@@ -0,0 +1,285 @@
+package handler
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/fahri/go-tije/internal/domain"
+	"github.com/fahri/go-tije/internal/geofenceio"
+	"github.com/fahri/go-tije/internal/service"
+	"github.com/fahri/go-tije/pkg/geofence"
+	"github.com/gofiber/fiber/v2"
+)
+
+type GeofenceHandler struct {
+	service service.GeofenceService
+}
+
+func NewGeofenceHandler(service service.GeofenceService) *GeofenceHandler {
+	return &GeofenceHandler{
+		service: service,
+	}
+}
+
+type geofenceRequest struct {
+	Name               string                   `json:"name"`
+	Type               string                   `json:"type"`
+	Latitude           float64                  `json:"latitude"`
+	Longitude          float64                  `json:"longitude"`
+	Radius             float64                  `json:"radius"`
+	Polygon            geofence.MultiPolygon    `json:"polygon"`
+	Line               geofence.Polyline        `json:"line"`
+	VehicleIDs         []string                 `json:"vehicle_ids"`
+	GroupIDs           []string                 `json:"group_ids"`
+	DwellSeconds       int                      `json:"dwell_seconds"`
+	HysteresisMeters   float64                  `json:"hysteresis_meters"`
+	MinPoints          int                      `json:"min_points"`
+	MinDurationSeconds int                      `json:"min_duration_seconds"`
+	DeviationSeconds   int                      `json:"deviation_seconds"`
+	Schedule           *domain.GeofenceSchedule `json:"schedule"`
+	Tags               []string                 `json:"tags"`
+	Active             *bool                    `json:"active"`
+}
+
+func (r *geofenceRequest) toGeofence() *domain.Geofence {
+	active := true
+	if r.Active != nil {
+		active = *r.Active
+	}
+
+	return &domain.Geofence{
+		Name:               r.Name,
+		Type:               r.Type,
+		Latitude:           r.Latitude,
+		Longitude:          r.Longitude,
+		Radius:             r.Radius,
+		Polygon:            r.Polygon,
+		Line:               r.Line,
+		VehicleIDs:         r.VehicleIDs,
+		GroupIDs:           r.GroupIDs,
+		DwellSeconds:       r.DwellSeconds,
+		HysteresisMeters:   r.HysteresisMeters,
+		MinPoints:          r.MinPoints,
+		MinDurationSeconds: r.MinDurationSeconds,
+		DeviationSeconds:   r.DeviationSeconds,
+		Schedule:           r.Schedule,
+		Tags:               r.Tags,
+		Active:             active,
+	}
+}
+
+func (h *GeofenceHandler) List(c *fiber.Ctx) error {
+	geofences, err := h.service.List(c.Context())
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to list geofences",
+		})
+	}
+
+	if geofences == nil {
+		geofences = []*domain.Geofence{}
+	}
+
+	return c.JSON(geofences)
+}
+
+func (h *GeofenceHandler) Get(c *fiber.Ctx) error {
+	geofence, err := h.service.Get(c.Context(), c.Params("id"))
+	if err != nil {
+		return geofenceError(c, err, "failed to get geofence")
+	}
+
+	return c.JSON(geofence)
+}
+
+func (h *GeofenceHandler) Create(c *fiber.Ctx) error {
+	var req geofenceRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid request body",
+		})
+	}
+
+	geofence := req.toGeofence()
+	if err := geofence.Validate(); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	if err := h.service.Create(c.Context(), geofence); err != nil {
+		return geofenceError(c, err, "failed to create geofence")
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(geofence)
+}
+
+func (h *GeofenceHandler) Update(c *fiber.Ctx) error {
+	var req geofenceRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid request body",
+		})
+	}
+
+	geofence := req.toGeofence()
+	geofence.ID = c.Params("id")
+	if err := geofence.Validate(); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	if err := h.service.Update(c.Context(), geofence); err != nil {
+		return geofenceError(c, err, "failed to update geofence")
+	}
+
+	return c.JSON(geofence)
+}
+
+func (h *GeofenceHandler) Delete(c *fiber.Ctx) error {
+	if err := h.service.Delete(c.Context(), c.Params("id")); err != nil {
+		return geofenceError(c, err, "failed to delete geofence")
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// Import accepts a GeoJSON or KML document either as the raw request body or
+// as a multipart "file" upload. The format comes from the "format" query
+// parameter, the file extension or the content type, in that order.
+func (h *GeofenceHandler) Import(c *fiber.Ctx) error {
+	data := c.Body()
+	format := c.Query("format")
+
+	if file, err := c.FormFile("file"); err == nil {
+		f, err := file.Open()
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "failed to read uploaded file",
+			})
+		}
+		defer f.Close()
+
+		if data, err = io.ReadAll(f); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "failed to read uploaded file",
+			})
+		}
+		if format == "" {
+			format = strings.TrimPrefix(strings.ToLower(filepath.Ext(file.Filename)), ".")
+		}
+	}
+
+	if format == "" {
+		format = formatFromContentType(c.Get(fiber.HeaderContentType))
+	}
+
+	var geofences []*domain.Geofence
+	var err error
+	switch format {
+	case "geojson", "json":
+		geofences, err = geofenceio.DecodeGeoJSON(data)
+	case "kml":
+		geofences, err = geofenceio.DecodeKML(data)
+	default:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "format must be geojson or kml",
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": fmt.Sprintf("invalid %s: %v", format, err),
+		})
+	}
+
+	ids := make(map[string]int, len(geofences))
+	for i, geofence := range geofences {
+		err := geofence.Validate()
+		if err == nil && len(geofence.ID) > 36 {
+			err = fmt.Errorf("id must be at most 36 characters")
+		}
+		if first, ok := ids[geofence.ID]; err == nil && ok {
+			err = fmt.Errorf("id %q is already used by geofence %d", geofence.ID, first)
+		}
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": fmt.Sprintf("geofence %d (%s): %v", i, geofence.Name, err),
+			})
+		}
+		if geofence.ID != "" {
+			ids[geofence.ID] = i
+		}
+	}
+
+	if err := h.service.Import(c.Context(), geofences); err != nil {
+		return geofenceError(c, err, "failed to import geofences")
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(geofences)
+}
+
+func (h *GeofenceHandler) Export(c *fiber.Ctx) error {
+	geofences, err := h.service.List(c.Context())
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to list geofences",
+		})
+	}
+
+	switch c.Query("format", "geojson") {
+	case "geojson":
+		data, err := geofenceio.EncodeGeoJSON(geofences)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "failed to export geofences",
+			})
+		}
+		c.Set(fiber.HeaderContentType, "application/geo+json")
+		c.Set(fiber.HeaderContentDisposition, `attachment; filename="geofences.geojson"`)
+		return c.Send(data)
+	case "kml":
+		data, err := geofenceio.EncodeKML(geofences)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "failed to export geofences",
+			})
+		}
+		c.Set(fiber.HeaderContentType, "application/vnd.google-earth.kml+xml")
+		c.Set(fiber.HeaderContentDisposition, `attachment; filename="geofences.kml"`)
+		return c.Send(data)
+	default:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "format must be geojson or kml",
+		})
+	}
+}
+
+func formatFromContentType(contentType string) string {
+	switch {
+	case strings.Contains(contentType, "kml"), strings.Contains(contentType, "xml"):
+		return "kml"
+	case strings.Contains(contentType, "json"):
+		return "geojson"
+	default:
+		return ""
+	}
+}
+
+func geofenceError(c *fiber.Ctx, err error, message string) error {
+	switch err.Error() {
+	case "geofence not found":
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	case "group not found":
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"error": message,
+	})
+}
@@ -0,0 +1,149 @@
+package handler
+
+import (
+	"github.com/fahri/go-tije/internal/domain"
+	"github.com/fahri/go-tije/internal/service"
+	"github.com/gofiber/fiber/v2"
+)
+
+type GroupHandler struct {
+	service service.GroupService
+}
+
+func NewGroupHandler(service service.GroupService) *GroupHandler {
+	return &GroupHandler{
+		service: service,
+	}
+}
+
+type groupRequest struct {
+	Name       string   `json:"name"`
+	Type       string   `json:"type"`
+	VehicleIDs []string `json:"vehicle_ids"`
+}
+
+func (h *GroupHandler) List(c *fiber.Ctx) error {
+	groups, err := h.service.List(c.Context())
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to list groups",
+		})
+	}
+
+	if groups == nil {
+		groups = []*domain.VehicleGroup{}
+	}
+
+	return c.JSON(groups)
+}
+
+func (h *GroupHandler) Get(c *fiber.Ctx) error {
+	group, err := h.service.Get(c.Context(), c.Params("id"))
+	if err != nil {
+		return groupError(c, err, "failed to get group")
+	}
+
+	return c.JSON(group)
+}
+
+func (h *GroupHandler) Create(c *fiber.Ctx) error {
+	var req groupRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid request body",
+		})
+	}
+
+	group := &domain.VehicleGroup{
+		Name:       req.Name,
+		Type:       req.Type,
+		VehicleIDs: req.VehicleIDs,
+	}
+	if err := group.Validate(); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	if err := h.service.Create(c.Context(), group); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to create group",
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(group)
+}
+
+func (h *GroupHandler) Update(c *fiber.Ctx) error {
+	var req groupRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid request body",
+		})
+	}
+
+	group := &domain.VehicleGroup{
+		ID:         c.Params("id"),
+		Name:       req.Name,
+		Type:       req.Type,
+		VehicleIDs: req.VehicleIDs,
+	}
+	if err := group.Validate(); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	if err := h.service.Update(c.Context(), group); err != nil {
+		return groupError(c, err, "failed to update group")
+	}
+
+	return c.JSON(group)
+}
+
+func (h *GroupHandler) Delete(c *fiber.Ctx) error {
+	if err := h.service.Delete(c.Context(), c.Params("id")); err != nil {
+		return groupError(c, err, "failed to delete group")
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func (h *GroupHandler) AddVehicle(c *fiber.Ctx) error {
+	vehicleID := c.Params("vehicle_id")
+	if vehicleID == "" || len(vehicleID) > 50 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "vehicle_id must be 1 to 50 characters",
+		})
+	}
+
+	if err := h.service.AddVehicle(c.Context(), c.Params("id"), vehicleID); err != nil {
+		return groupError(c, err, "failed to add vehicle to group")
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func (h *GroupHandler) RemoveVehicle(c *fiber.Ctx) error {
+	if err := h.service.RemoveVehicle(c.Context(), c.Params("id"), c.Params("vehicle_id")); err != nil {
+		return groupError(c, err, "failed to remove vehicle from group")
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func groupError(c *fiber.Ctx, err error, message string) error {
+	switch err.Error() {
+	case "group not found", "group member not found":
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	case "group is assigned to geofences":
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"error": message,
+	})
+}
@@ -0,0 +1,206 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/fahri/go-tije/internal/codec"
+	"github.com/fahri/go-tije/internal/domain"
+	"github.com/fahri/go-tije/internal/service"
+	"github.com/gofiber/fiber/v2"
+)
+
+type IngestHandler struct {
+	service     service.VehicleService
+	credentials service.CredentialService
+	maxBatch    int
+}
+
+func NewIngestHandler(service service.VehicleService, credentials service.CredentialService, maxBatch int) *IngestHandler {
+	return &IngestHandler{
+		service:     service,
+		credentials: credentials,
+		maxBatch:    maxBatch,
+	}
+}
+
+// Result statuses of one location posted to Locations.
+const (
+	ingestAccepted  = "accepted"
+	ingestDuplicate = "duplicate"
+	ingestRejected  = "rejected"
+	ingestForbidden = "forbidden"
+	ingestFailed    = "failed"
+)
+
+type ingestResult struct {
+	Index     int                 `json:"index"`
+	VehicleID string              `json:"vehicle_id,omitempty"`
+	Status    string              `json:"status"`
+	Reason    domain.RejectReason `json:"reason,omitempty"`
+	Error     string              `json:"error,omitempty"`
+}
+
+// Authenticate resolves the ingestion credential from an
+// "Authorization: Bearer <token>" or "X-API-Key" header.
+func (h *IngestHandler) Authenticate(c *fiber.Ctx) error {
+	credential, err := h.credentials.Authenticate(c.Context(), requestToken(c))
+	if err != nil {
+		if err.Error() == "invalid credential" {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "missing or invalid API key",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to authenticate",
+		})
+	}
+
+	c.Locals("credential", credential)
+	return c.Next()
+}
+
+// Locations ingests a JSON array of locations in the MQTT JSON format, in
+// order, and reports a result per item. Items for vehicles outside the
+// credential's scope are refused as forbidden; a device credential fills in
+// its vehicle when vehicle_id is omitted.
+func (h *IngestHandler) Locations(c *fiber.Ctx) error {
+	credential := c.Locals("credential").(*domain.IngestCredential)
+
+	var items []json.RawMessage
+	if err := json.Unmarshal(c.Body(), &items); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "request body must be a JSON array of locations",
+		})
+	}
+	if len(items) == 0 || len(items) > h.maxBatch {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "request must contain 1 to " + strconv.Itoa(h.maxBatch) + " locations",
+		})
+	}
+
+	results := make([]ingestResult, len(items))
+	counts := make(map[string]int)
+	for i, item := range items {
+		results[i] = h.ingest(c, credential, item)
+		results[i].Index = i
+		counts[results[i].Status]++
+	}
+
+	return c.JSON(fiber.Map{
+		"counts":  counts,
+		"results": results,
+	})
+}
+
+func (h *IngestHandler) ingest(c *fiber.Ctx, credential *domain.IngestCredential, item json.RawMessage) ingestResult {
+	message, err := codec.Decode(codec.FormatJSON, item)
+	if err != nil {
+		// Let the service record the undecodable payload.
+		err = h.service.ProcessPayload(c.Context(), codec.FormatJSON, item)
+		return ingestOutcome("", err)
+	}
+
+	if message.VehicleID == "" && credential.Type == domain.CredentialTypeDevice {
+		message.VehicleID = credential.VehicleID
+	}
+	if message.VehicleID != "" && !credential.Allows(message.VehicleID) {
+		return ingestResult{
+			VehicleID: message.VehicleID,
+			Status:    ingestForbidden,
+			Error:     "credential may not report this vehicle",
+		}
+	}
+
+	return ingestOutcome(message.VehicleID, h.service.ProcessMessage(c.Context(), message))
+}
+
+func ingestOutcome(vehicleID string, err error) ingestResult {
+	result := ingestResult{VehicleID: vehicleID, Status: ingestAccepted}
+
+	var validationErr *domain.ValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		result.Status = ingestRejected
+		result.Reason = validationErr.Reason
+		result.Error = validationErr.Message
+	case errors.Is(err, domain.ErrDuplicateLocation):
+		result.Status = ingestDuplicate
+	case err != nil:
+		log.Printf("Failed to ingest location for %s: %v", vehicleID, err)
+		result.Status = ingestFailed
+		result.Error = "failed to store location"
+	}
+
+	return result
+}
+
+// Rejections reports how many locations were rejected per reason between
+// start and end (Unix seconds), defaulting to the last 24 hours.
+func (h *IngestHandler) Rejections(c *fiber.Ctx) error {
+	end := time.Now().Unix()
+	start := end - int64((24 * time.Hour).Seconds())
+
+	var err error
+	if v := c.Query("start"); v != "" {
+		if start, err = strconv.ParseInt(v, 10, 64); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "invalid start timestamp",
+			})
+		}
+	}
+	if v := c.Query("end"); v != "" {
+		if end, err = strconv.ParseInt(v, 10, 64); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "invalid end timestamp",
+			})
+		}
+	}
+
+	counts, err := h.service.GetRejectionCounts(c.Context(), start, end)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to count rejections",
+		})
+	}
+
+	var total int64
+	for _, count := range counts {
+		total += count
+	}
+
+	return c.JSON(fiber.Map{
+		"start":   start,
+		"end":     end,
+		"total":   total,
+		"reasons": counts,
+	})
+}
+
+// RecentRejections lists the latest rejected messages, optionally filtered by
+// reason.
+func (h *IngestHandler) RecentRejections(c *fiber.Ctx) error {
+	limit := c.QueryInt("limit", 50)
+	if limit <= 0 || limit > 500 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "limit must be between 1 and 500",
+		})
+	}
+
+	reason := domain.RejectReason(c.Query("reason"))
+	rejections, err := h.service.GetRecentRejections(c.Context(), reason, limit)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to list rejections",
+		})
+	}
+
+	if rejections == nil {
+		rejections = []*domain.InvalidLocation{}
+	}
+
+	return c.JSON(rejections)
+}
@@ -8,12 +8,14 @@ import (
 )
 
 type VehicleHandler struct {
-	service service.VehicleService
+	service  service.VehicleService
+	presence service.PresenceService
 }
 
-func NewVehicleHandler(service service.VehicleService) *VehicleHandler {
+func NewVehicleHandler(service service.VehicleService, presence service.PresenceService) *VehicleHandler {
 	return &VehicleHandler{
-		service: service,
+		service:  service,
+		presence: presence,
 	}
 }
 
@@ -40,6 +42,31 @@ func (h *VehicleHandler) GetLatestLocation(c *fiber.Ctx) error {
 	return c.JSON(location)
 }
 
+// GetStatus reports whether the vehicle's tracker is online and when it was
+// last heard from.
+func (h *VehicleHandler) GetStatus(c *fiber.Ctx) error {
+	vehicleID := c.Params("vehicle_id")
+	if vehicleID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "vehicle_id is required",
+		})
+	}
+	
+	presence, err := h.presence.GetPresence(c.Context(), vehicleID)
+	if err != nil {
+		if err.Error() == "vehicle not found" {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to get status",
+		})
+	}
+	
+	return c.JSON(presence)
+}
+
 func (h *VehicleHandler) GetLocationHistory(c *fiber.Ctx) error {
 	vehicleID := c.Params("vehicle_id")
 	if vehicleID == "" {
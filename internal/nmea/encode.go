@@ -0,0 +1,62 @@
+package nmea
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/fahri/go-tije/internal/domain"
+)
+
+// Encode renders a location as a $GPRMC and a $GPGGA sentence, one per line.
+func Encode(message *domain.LocationMessage) []byte {
+	ts := time.Unix(message.Timestamp, 0).UTC()
+	clock := ts.Format("150405") + ".00"
+	lat := formatCoordinate(message.Latitude, 2, "N", "S")
+	lon := formatCoordinate(message.Longitude, 3, "E", "W")
+
+	quality, satellites := 1, ""
+	if message.FixQuality != nil {
+		quality = *message.FixQuality
+	}
+	if message.Satellites != nil {
+		satellites = fmt.Sprintf("%02d", *message.Satellites)
+	}
+
+	rmc := fmt.Sprintf("GPRMC,%s,A,%s,%s,%s,%s,%s,,,A", clock, lat, lon,
+		formatOptional(message.Speed, 1/knotsToKmh, 1), formatOptional(message.Heading, 1, 1), ts.Format("020106"))
+	gga := fmt.Sprintf("GPGGA,%s,%s,%s,%d,%s,%s,%s,M,,M,,", clock, lat, lon, quality, satellites,
+		formatOptional(message.HDOP, 1, 1), formatOptional(message.Altitude, 1, 1))
+
+	return []byte(withChecksum(rmc) + "\r\n" + withChecksum(gga) + "\r\n")
+}
+
+func withChecksum(body string) string {
+	var sum byte
+	for i := 0; i < len(body); i++ {
+		sum ^= body[i]
+	}
+	return fmt.Sprintf("$%s*%02X", body, sum)
+}
+
+// formatCoordinate renders ddmm.mmmm,N (or dddmm.mmmm,E).
+func formatCoordinate(value float64, degreeDigits int, positive, negative string) string {
+	hemisphere := positive
+	if value < 0 {
+		hemisphere, value = negative, -value
+	}
+
+	degrees := math.Floor(value)
+	minutes := math.Round((value-degrees)*60*1e4) / 1e4
+	if minutes >= 60 {
+		degrees, minutes = degrees+1, 0
+	}
+	return fmt.Sprintf("%0*d%07.4f,%s", degreeDigits, int(degrees), minutes, hemisphere)
+}
+
+func formatOptional(value *float64, scale float64, decimals int) string {
+	if value == nil {
+		return ""
+	}
+	return fmt.Sprintf("%.*f", decimals, *value*scale)
+}
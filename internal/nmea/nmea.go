@@ -0,0 +1,397 @@
+// Package nmea converts NMEA 0183 RMC and GGA sentences into location
+// messages.
+package nmea
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fahri/go-tije/internal/domain"
+)
+
+const knotsToKmh = 1.852
+
+// Sentence is a checksum-verified sentence split into its fields. Fields[0]
+// is the address, such as "GPRMC".
+type Sentence struct {
+	Talker string
+	Type   string
+	Fields []string
+}
+
+// ParseSentence checks the framing and the mandatory *hh checksum of a
+// sentence such as "$GPRMC,...*6A".
+func ParseSentence(line string) (*Sentence, error) {
+	line = strings.TrimSpace(line)
+	if !strings.HasPrefix(line, "$") {
+		return nil, fmt.Errorf("sentence must start with $")
+	}
+
+	star := strings.LastIndexByte(line, '*')
+	if star < 0 || len(line)-star != 3 {
+		return nil, fmt.Errorf("sentence has no checksum")
+	}
+	want, err := strconv.ParseUint(line[star+1:], 16, 8)
+	if err != nil {
+		return nil, fmt.Errorf("invalid checksum %q", line[star+1:])
+	}
+
+	body := line[1:star]
+	var sum byte
+	for i := 0; i < len(body); i++ {
+		sum ^= body[i]
+	}
+	if sum != byte(want) {
+		return nil, fmt.Errorf("checksum mismatch: got %02X, want %02X", sum, want)
+	}
+
+	fields := strings.Split(body, ",")
+	if len(fields[0]) != 5 {
+		return nil, fmt.Errorf("invalid address %q", fields[0])
+	}
+
+	return &Sentence{
+		Talker: fields[0][:2],
+		Type:   fields[0][2:],
+		Fields: fields,
+	}, nil
+}
+
+// fix collects the RMC and GGA sentences that describe one position.
+type fix struct {
+	timeOfDay  time.Duration
+	date       time.Time
+	hasDate    bool
+	valid      bool
+	latitude   float64
+	longitude  float64
+	speed      *float64
+	heading    *float64
+	altitude   *float64
+	hdop       *float64
+	satellites *int
+	quality    *int
+}
+
+// Decoder merges the RMC and GGA sentences of each fix and emits one location
+// message per fix. RMC supplies the date, speed and course, GGA the fix
+// quality, satellite count, HDOP and altitude. A GGA-only fix takes its date
+// from the last RMC, or from the current date when none was seen.
+type Decoder struct {
+	vehicleID string
+	pending   *fix
+	lastDate  time.Time
+	now       func() time.Time
+}
+
+func NewDecoder(vehicleID string) *Decoder {
+	return &Decoder{
+		vehicleID: vehicleID,
+		now:       time.Now,
+	}
+}
+
+// Feed adds a sentence. When it starts a new fix, the previous one is
+// returned. Sentence types other than RMC and GGA are ignored.
+func (d *Decoder) Feed(line string) (*domain.LocationMessage, error) {
+	sentence, err := ParseSentence(line)
+	if err != nil {
+		return nil, err
+	}
+	if sentence.Type != "RMC" && sentence.Type != "GGA" {
+		return nil, nil
+	}
+	if len(sentence.Fields) < 2 {
+		return nil, fmt.Errorf("%s sentence has no time", sentence.Type)
+	}
+
+	// Receivers mix precisions such as "123519" and "123519.00" for one
+	// fix, so sentences are grouped by the parsed time of day.
+	var done *domain.LocationMessage
+	var timeOfDay time.Duration
+	var clockErr error
+	clock := sentence.Fields[1]
+	if clock != "" {
+		timeOfDay, clockErr = parseTime(clock)
+	}
+	if d.pending != nil && clock != "" && (clockErr != nil || d.pending.timeOfDay != timeOfDay) {
+		done = d.Flush()
+	}
+
+	// Receivers without a fix keep sending void RMC and quality 0 GGA
+	// sentences, often without a time; they carry no position to report.
+	if !hasFix(sentence) {
+		if d.pending != nil && clock != "" {
+			d.pending.valid = false
+		}
+		return done, nil
+	}
+	if clock == "" {
+		return done, fmt.Errorf("%s sentence has no time", sentence.Type)
+	}
+	if d.pending == nil {
+		if clockErr != nil {
+			return done, clockErr
+		}
+		d.pending = &fix{timeOfDay: timeOfDay, valid: true}
+	}
+
+	if sentence.Type == "RMC" {
+		err = d.pending.applyRMC(sentence.Fields)
+	} else {
+		err = d.pending.applyGGA(sentence.Fields)
+	}
+	if err != nil {
+		d.pending = nil
+	}
+	return done, err
+}
+
+// Flush returns the fix still being collected, or nil when there is none or
+// it has no valid position.
+func (d *Decoder) Flush() *domain.LocationMessage {
+	f := d.pending
+	d.pending = nil
+	if f == nil || !f.valid {
+		return nil
+	}
+
+	if f.hasDate {
+		d.lastDate = f.date
+	}
+	date := f.date
+	if !f.hasDate {
+		date = d.lastDate
+	}
+	if date.IsZero() {
+		// Without any RMC, pick the date that puts the fix closest to now.
+		now := d.now().UTC()
+		date = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+		if date.Add(f.timeOfDay).Sub(now) > 12*time.Hour {
+			date = date.AddDate(0, 0, -1)
+		}
+	}
+
+	message := &domain.LocationMessage{
+		VehicleID: d.vehicleID,
+		Latitude:  f.latitude,
+		Longitude: f.longitude,
+		Timestamp: date.Add(f.timeOfDay).Unix(),
+	}
+	message.Speed = f.speed
+	message.Heading = f.heading
+	message.Altitude = f.altitude
+	message.HDOP = f.hdop
+	message.Satellites = f.satellites
+	message.FixQuality = f.quality
+
+	return message
+}
+
+// Parse decodes every fix in data, one sentence per line, such as an MQTT
+// payload holding the RMC and GGA sentences of a fix. Invalid sentences are
+// skipped; their errors are returned joined alongside the fixes that were
+// decoded.
+func Parse(vehicleID string, data []byte) ([]*domain.LocationMessage, error) {
+	var messages []*domain.LocationMessage
+	var errs []error
+	err := decode(bytes.NewReader(data), vehicleID, func(message *domain.LocationMessage) error {
+		messages = append(messages, message)
+		return nil
+	}, func(err error) {
+		errs = append(errs, err)
+	})
+	if err != nil {
+		return messages, err
+	}
+	return messages, errors.Join(errs...)
+}
+
+// Decode reads sentences from r, such as a recorded log file, and calls fn
+// for each fix in order. Invalid sentences are logged and skipped.
+func Decode(r io.Reader, vehicleID string, fn func(*domain.LocationMessage) error) error {
+	return decode(r, vehicleID, fn, func(err error) {
+		log.Printf("Skipped NMEA sentence: %v", err)
+	})
+}
+
+func decode(r io.Reader, vehicleID string, fn func(*domain.LocationMessage) error, skip func(error)) error {
+	decoder := NewDecoder(vehicleID)
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+
+		message, err := decoder.Feed(text)
+		if err != nil {
+			skip(fmt.Errorf("line %d: %v", line, err))
+		}
+		if message != nil {
+			if err := fn(message); err != nil {
+				return err
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	if message := decoder.Flush(); message != nil {
+		return fn(message)
+	}
+	return nil
+}
+
+// applyRMC reads $--RMC,time,status,lat,N/S,lon,E/W,knots,course,date,...
+func (f *fix) applyRMC(fields []string) error {
+	if len(fields) < 10 {
+		return fmt.Errorf("RMC sentence has %d fields", len(fields))
+	}
+
+	if fields[2] != "A" {
+		f.valid = false
+		return nil
+	}
+
+	date, err := time.Parse("020106", fields[9])
+	if err != nil {
+		return fmt.Errorf("invalid RMC date %q", fields[9])
+	}
+	f.date, f.hasDate = date, true
+
+	if err := f.setPosition(fields[3:7]); err != nil {
+		return err
+	}
+
+	if f.speed, err = parseOptional(fields[7]); err != nil {
+		return fmt.Errorf("invalid RMC speed %q", fields[7])
+	}
+	if f.speed != nil {
+		*f.speed *= knotsToKmh
+	}
+	if f.heading, err = parseOptional(fields[8]); err != nil {
+		return fmt.Errorf("invalid RMC course %q", fields[8])
+	}
+	return nil
+}
+
+// applyGGA reads $--GGA,time,lat,N/S,lon,E/W,quality,satellites,hdop,altitude,M,...
+func (f *fix) applyGGA(fields []string) error {
+	if len(fields) < 10 {
+		return fmt.Errorf("GGA sentence has %d fields", len(fields))
+	}
+
+	quality, err := strconv.Atoi(fields[6])
+	if err != nil {
+		return fmt.Errorf("invalid GGA fix quality %q", fields[6])
+	}
+	f.quality = &quality
+	if quality == 0 {
+		f.valid = false
+		return nil
+	}
+	if err := f.setPosition(fields[2:6]); err != nil {
+		return err
+	}
+
+	if fields[7] != "" {
+		satellites, err := strconv.Atoi(fields[7])
+		if err != nil {
+			return fmt.Errorf("invalid GGA satellite count %q", fields[7])
+		}
+		f.satellites = &satellites
+	}
+	if f.hdop, err = parseOptional(fields[8]); err != nil {
+		return fmt.Errorf("invalid GGA HDOP %q", fields[8])
+	}
+	if f.altitude, err = parseOptional(fields[9]); err != nil {
+		return fmt.Errorf("invalid GGA altitude %q", fields[9])
+	}
+	return nil
+}
+
+// hasFix reports whether an RMC or GGA sentence carries a position: RMC
+// status A, or a GGA fix quality other than empty or 0.
+func hasFix(sentence *Sentence) bool {
+	if sentence.Type == "RMC" {
+		return len(sentence.Fields) > 2 && sentence.Fields[2] == "A"
+	}
+	return len(sentence.Fields) > 6 && sentence.Fields[6] != "" && sentence.Fields[6] != "0"
+}
+
+// setPosition reads the lat, N/S, lon, E/W fields.
+func (f *fix) setPosition(fields []string) error {
+	lat, err := parseCoordinate(fields[0], fields[1], 2, "N", "S")
+	if err != nil {
+		return err
+	}
+	lon, err := parseCoordinate(fields[2], fields[3], 3, "E", "W")
+	if err != nil {
+		return err
+	}
+
+	f.latitude, f.longitude = lat, lon
+	return nil
+}
+
+// parseCoordinate converts ddmm.mmmm (or dddmm.mmmm) with its hemisphere.
+func parseCoordinate(value, hemisphere string, degreeDigits int, positive, negative string) (float64, error) {
+	if len(value) < degreeDigits+2 {
+		return 0, fmt.Errorf("invalid coordinate %q", value)
+	}
+
+	degrees, err := strconv.ParseFloat(value[:degreeDigits], 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid coordinate %q", value)
+	}
+	minutes, err := strconv.ParseFloat(value[degreeDigits:], 64)
+	if err != nil || minutes >= 60 {
+		return 0, fmt.Errorf("invalid coordinate %q", value)
+	}
+
+	coordinate := degrees + minutes/60
+	switch hemisphere {
+	case positive:
+		return coordinate, nil
+	case negative:
+		return -coordinate, nil
+	}
+	return 0, fmt.Errorf("invalid hemisphere %q", hemisphere)
+}
+
+// parseTime converts hhmmss(.sss) into the time since midnight UTC.
+func parseTime(value string) (time.Duration, error) {
+	if len(value) < 6 {
+		return 0, fmt.Errorf("invalid time %q", value)
+	}
+
+	hours, err1 := strconv.Atoi(value[0:2])
+	minutes, err2 := strconv.Atoi(value[2:4])
+	seconds, err3 := strconv.ParseFloat(value[4:], 64)
+	if err1 != nil || err2 != nil || err3 != nil || hours > 23 || minutes > 59 || seconds >= 61 {
+		return 0, fmt.Errorf("invalid time %q", value)
+	}
+
+	return time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute +
+		time.Duration(seconds*float64(time.Second)), nil
+}
+
+func parseOptional(value string) (*float64, error) {
+	if value == "" {
+		return nil, nil
+	}
+	v, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
@@ -0,0 +1,140 @@
+package nmea
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fahri/go-tije/internal/domain"
+)
+
+// coldStart is what a u-blox receiver sends after power-up, before it has a
+// fix, followed by its first fix.
+const coldStart = `$GPRMC,,V,,,,,,,,,,N*53
+$GPVTG,,,,,,,,,N*30
+$GPGGA,,,,,,0,00,99.99,,,,,,*48
+$GPGSA,A,1,,,,,,,,,,,,,99.99,99.99,99.99*30
+$GPGSV,1,1,00*79
+$GPGLL,,,,,,V,N*64
+$GPRMC,123518,V,,,,,,,,,,N*5F
+$GPGGA,123518.00,,,,,0,00,99.99,,,,,,*6A
+$GPRMC,123519,A,4807.038,N,01131.000,E,022.4,084.4,230394,003.1,W*6A
+$GPGGA,123519,4807.038,N,01131.000,E,1,08,0.9,545.4,M,46.9,M,,*47
+`
+
+func TestParseSkipsSentencesWithoutFix(t *testing.T) {
+	messages, err := Parse("B1234XYZ", []byte(coldStart))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if len(messages) != 1 {
+		t.Fatalf("got %d fixes, want 1", len(messages))
+	}
+
+	m := messages[0]
+	want := time.Date(1994, 3, 23, 12, 35, 19, 0, time.UTC).Unix()
+	if m.Timestamp != want {
+		t.Errorf("timestamp = %d, want %d", m.Timestamp, want)
+	}
+	if !near(m.Latitude, 48.1173) || !near(m.Longitude, 11.516667) {
+		t.Errorf("position = %f, %f", m.Latitude, m.Longitude)
+	}
+	if m.Speed == nil || !near(*m.Speed, 22.4*knotsToKmh) {
+		t.Errorf("speed = %v, want %f", m.Speed, 22.4*knotsToKmh)
+	}
+	if m.Satellites == nil || *m.Satellites != 8 {
+		t.Errorf("satellites = %v, want 8", m.Satellites)
+	}
+	if m.FixQuality == nil || *m.FixQuality != 1 {
+		t.Errorf("fix quality = %v, want 1", m.FixQuality)
+	}
+	if m.Altitude == nil || !near(*m.Altitude, 545.4) {
+		t.Errorf("altitude = %v, want 545.4", m.Altitude)
+	}
+}
+
+func TestParseNoFix(t *testing.T) {
+	tests := []string{
+		"$GPRMC,,V,,,,,,,,,,N*53",
+		"$GPGGA,,,,,,0,00,99.99,,,,,,*48",
+		"$GPRMC,123518,V,,,,,,,,,,N*5F",
+		"$GPGGA,123518.00,,,,,0,00,99.99,,,,,,*6A",
+	}
+	for _, line := range tests {
+		messages, err := Parse("B1234XYZ", []byte(line))
+		if err != nil || len(messages) != 0 {
+			t.Errorf("%s: got %d fixes, err %v; want none", line, len(messages), err)
+		}
+	}
+}
+
+func TestParseSkipsMalformedSentences(t *testing.T) {
+	// The second RMC has a corrupted checksum, as seen on noisy serial lines.
+	data := `$GPRMC,083559.00,A,4717.11437,N,00833.91522,E,0.004,77.52,091202,,,A*57
+$GPRMC,083600.00,A,4717.11437,N,00833.91522,E,0.004,77.52,091202,,,A*00
+$GPGGA,092725.00,4717.11399,N,00833.91590,E,1,08,1.01,499.6,M,48.0,M,,*5B
+`
+	messages, err := Parse("B1234XYZ", []byte(data))
+	if err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("err = %v, want the line 2 checksum error", err)
+	}
+	if len(messages) != 2 {
+		t.Fatalf("got %d fixes, want 2", len(messages))
+	}
+
+	// The GGA-only fix takes its date from the earlier RMC.
+	want := time.Date(2002, 12, 9, 9, 27, 25, 0, time.UTC).Unix()
+	if messages[1].Timestamp != want {
+		t.Errorf("GGA timestamp = %d, want %d", messages[1].Timestamp, want)
+	}
+	if messages[1].HDOP == nil || !near(*messages[1].HDOP, 1.01) {
+		t.Errorf("HDOP = %v, want 1.01", messages[1].HDOP)
+	}
+}
+
+func TestParseMixedTimePrecision(t *testing.T) {
+	// RMC and GGA of one fix may write the same time with different
+	// precision; they still describe a single fix.
+	data := `$GPRMC,123519,A,4807.038,N,01131.000,E,022.4,084.4,230394,003.1,W*6A
+$GPGGA,123519.00,4807.038,N,01131.000,E,1,08,0.9,545.4,M,46.9,M,,*69
+$GPRMC,123520.0,A,4807.040,N,01131.002,E,022.4,084.4,230394,003.1,W*73
+$GPGGA,123520,4807.040,N,01131.002,E,1,08,0.9,545.4,M,46.9,M,,*40
+`
+	messages, err := Parse("B1234XYZ", []byte(data))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if len(messages) != 2 {
+		t.Fatalf("got %d fixes, want 2", len(messages))
+	}
+
+	for i, m := range messages {
+		want := time.Date(1994, 3, 23, 12, 35, 19+i, 0, time.UTC).Unix()
+		if m.Timestamp != want {
+			t.Errorf("fix %d: timestamp = %d, want %d", i, m.Timestamp, want)
+		}
+		if m.Speed == nil || m.Satellites == nil || *m.Satellites != 8 {
+			t.Errorf("fix %d: RMC and GGA were not merged: speed %v, satellites %v", i, m.Speed, m.Satellites)
+		}
+	}
+}
+
+func TestDecodeContinuesPastBadLines(t *testing.T) {
+	data := "garbage\n" + coldStart
+	var count int
+	err := Decode(strings.NewReader(data), "B1234XYZ", func(*domain.LocationMessage) error {
+		count++
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("got %d fixes, want 1", count)
+	}
+}
+
+func near(a, b float64) bool {
+	return math.Abs(a-b) < 1e-4
+}
@@ -0,0 +1,167 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/fahri/go-tije/internal/domain"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type CommandRepository interface {
+	Create(ctx context.Context, command *domain.VehicleCommand, timeout time.Duration) error
+	FindByID(ctx context.Context, vehicleID, id string) (*domain.VehicleCommand, error)
+	FindByVehicle(ctx context.Context, vehicleID string, limit int) ([]*domain.VehicleCommand, error)
+	Resolve(ctx context.Context, vehicleID, id, status, message string) error
+	ExpirePending(ctx context.Context) (int64, error)
+}
+
+type commandRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewCommandRepository(db *pgxpool.Pool) CommandRepository {
+	return &commandRepository{db: db}
+}
+
+const commandColumns = `id, vehicle_id, type, params, status, COALESCE(error, ''),
+	expires_at, acked_at, created_at, updated_at`
+
+// Create stores a pending command that expires timeout from now.
+func (r *commandRepository) Create(ctx context.Context, command *domain.VehicleCommand, timeout time.Duration) error {
+	query := `
+		INSERT INTO vehicle_commands (id, vehicle_id, type, params, status, expires_at, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, NOW() + make_interval(secs => $6), NOW(), NOW())
+		RETURNING expires_at, created_at, updated_at
+	`
+
+	params, err := json.Marshal(command.Params)
+	if err != nil {
+		return err
+	}
+
+	command.ID = uuid.New().String()
+	command.Status = domain.CommandStatusPending
+	return r.db.QueryRow(ctx, query,
+		command.ID,
+		command.VehicleID,
+		command.Type,
+		params,
+		command.Status,
+		timeout.Seconds(),
+	).Scan(&command.ExpiresAt, &command.CreatedAt, &command.UpdatedAt)
+}
+
+func (r *commandRepository) FindByID(ctx context.Context, vehicleID, id string) (*domain.VehicleCommand, error) {
+	query := `
+		SELECT ` + commandColumns + `
+		FROM vehicle_commands
+		WHERE vehicle_id = $1 AND id = $2
+	`
+
+	command, err := scanCommand(r.db.QueryRow(ctx, query, vehicleID, id))
+	if err == pgx.ErrNoRows {
+		return nil, fmt.Errorf("command not found")
+	}
+
+	return command, err
+}
+
+// FindByVehicle returns the latest commands of a vehicle, newest first.
+func (r *commandRepository) FindByVehicle(ctx context.Context, vehicleID string, limit int) ([]*domain.VehicleCommand, error) {
+	query := `
+		SELECT ` + commandColumns + `
+		FROM vehicle_commands
+		WHERE vehicle_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2
+	`
+
+	rows, err := r.db.Query(ctx, query, vehicleID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var commands []*domain.VehicleCommand
+	for rows.Next() {
+		command, err := scanCommand(rows)
+		if err != nil {
+			return nil, err
+		}
+		commands = append(commands, command)
+	}
+
+	return commands, rows.Err()
+}
+
+// Resolve moves a pending, unexpired command to status. A command that was
+// already resolved or has expired is left alone.
+func (r *commandRepository) Resolve(ctx context.Context, vehicleID, id, status, message string) error {
+	query := `
+		UPDATE vehicle_commands
+		SET status = $3, error = $4,
+			acked_at = CASE WHEN $3 = 'acked' THEN NOW() END,
+			updated_at = NOW()
+		WHERE vehicle_id = $1 AND id = $2
+			AND status = 'pending' AND expires_at >= NOW()
+	`
+
+	tag, err := r.db.Exec(ctx, query, vehicleID, id, status, nullString(message))
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("command not pending")
+	}
+
+	return nil
+}
+
+// ExpirePending marks pending commands past their expiry as timed out.
+func (r *commandRepository) ExpirePending(ctx context.Context) (int64, error) {
+	query := `
+		UPDATE vehicle_commands
+		SET status = 'timed_out', updated_at = NOW()
+		WHERE status = 'pending' AND expires_at < NOW()
+	`
+
+	tag, err := r.db.Exec(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+
+	return tag.RowsAffected(), nil
+}
+
+func scanCommand(row pgx.Row) (*domain.VehicleCommand, error) {
+	var command domain.VehicleCommand
+	var params []byte
+	err := row.Scan(
+		&command.ID,
+		&command.VehicleID,
+		&command.Type,
+		&params,
+		&command.Status,
+		&command.Error,
+		&command.ExpiresAt,
+		&command.AckedAt,
+		&command.CreatedAt,
+		&command.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(params) > 0 {
+		if err := json.Unmarshal(params, &command.Params); err != nil {
+			return nil, err
+		}
+	}
+
+	return &command, nil
+}
@@ -0,0 +1,131 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/fahri/go-tije/internal/domain"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// CredentialRepository stores ingestion credentials. Only the SHA-256 hash of
+// a token is kept.
+type CredentialRepository interface {
+	FindAll(ctx context.Context) ([]*domain.IngestCredential, error)
+	Create(ctx context.Context, credential *domain.IngestCredential, tokenHash string) error
+	Delete(ctx context.Context, id string) error
+	Authenticate(ctx context.Context, tokenHash string) (*domain.IngestCredential, error)
+}
+
+type credentialRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewCredentialRepository(db *pgxpool.Pool) CredentialRepository {
+	return &credentialRepository{db: db}
+}
+
+const credentialColumns = `c.id, c.name, c.type, COALESCE(c.vehicle_id, ''), COALESCE(c.group_id, ''),
+	c.last_used_at, c.created_at`
+
+func (r *credentialRepository) FindAll(ctx context.Context) ([]*domain.IngestCredential, error) {
+	query := `
+		SELECT ` + credentialColumns + `
+		FROM ingest_credentials c
+		ORDER BY c.name
+	`
+
+	rows, err := r.db.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var credentials []*domain.IngestCredential
+	for rows.Next() {
+		var credential domain.IngestCredential
+		if err := rows.Scan(credentialFields(&credential)...); err != nil {
+			return nil, err
+		}
+		credentials = append(credentials, &credential)
+	}
+
+	return credentials, rows.Err()
+}
+
+func (r *credentialRepository) Create(ctx context.Context, credential *domain.IngestCredential, tokenHash string) error {
+	query := `
+		INSERT INTO ingest_credentials (id, name, type, vehicle_id, group_id, token_hash, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, NOW())
+		RETURNING created_at
+	`
+
+	credential.ID = uuid.New().String()
+	err := r.db.QueryRow(ctx, query,
+		credential.ID,
+		credential.Name,
+		credential.Type,
+		nullString(credential.VehicleID),
+		nullString(credential.GroupID),
+		tokenHash,
+	).Scan(&credential.CreatedAt)
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+		return fmt.Errorf("group not found")
+	}
+
+	return err
+}
+
+func (r *credentialRepository) Delete(ctx context.Context, id string) error {
+	tag, err := r.db.Exec(ctx, `DELETE FROM ingest_credentials WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("credential not found")
+	}
+
+	return nil
+}
+
+// Authenticate looks up the credential for a token hash, records its use and
+// loads the vehicles of a partner's group.
+func (r *credentialRepository) Authenticate(ctx context.Context, tokenHash string) (*domain.IngestCredential, error) {
+	query := `
+		UPDATE ingest_credentials c
+		SET last_used_at = NOW()
+		WHERE c.token_hash = $1
+		RETURNING ` + credentialColumns + `,
+			ARRAY(SELECT m.vehicle_id FROM vehicle_group_members m WHERE m.group_id = c.group_id)
+	`
+
+	var credential domain.IngestCredential
+	fields := append(credentialFields(&credential), &credential.VehicleIDs)
+	err := r.db.QueryRow(ctx, query, tokenHash).Scan(fields...)
+	if err == pgx.ErrNoRows {
+		return nil, fmt.Errorf("invalid credential")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &credential, nil
+}
+
+func credentialFields(credential *domain.IngestCredential) []interface{} {
+	return []interface{}{
+		&credential.ID,
+		&credential.Name,
+		&credential.Type,
+		&credential.VehicleID,
+		&credential.GroupID,
+		&credential.LastUsedAt,
+		&credential.CreatedAt,
+	}
+}
@@ -0,0 +1,392 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/fahri/go-tije/internal/domain"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// geofenceChannel is the Postgres NOTIFY channel used to signal changes to
+// zones and vehicle groups.
+const geofenceChannel = "geofences_changed"
+
+type execer interface {
+	Exec(ctx context.Context, sql string, arguments ...interface{}) (pgconn.CommandTag, error)
+}
+
+type GeofenceRepository interface {
+	FindActive(ctx context.Context) ([]*domain.Geofence, error)
+	FindAll(ctx context.Context) ([]*domain.Geofence, error)
+	FindByID(ctx context.Context, id string) (*domain.Geofence, error)
+	Create(ctx context.Context, geofence *domain.Geofence) error
+	Update(ctx context.Context, geofence *domain.Geofence) error
+	Delete(ctx context.Context, id string) error
+	// Import writes all zones in one transaction. A zone with an ID replaces
+	// the stored zone with that ID, or is created under it; the others get a
+	// new ID.
+	Import(ctx context.Context, geofences []*domain.Geofence) error
+	Listen(ctx context.Context, onChange func(id string)) error
+}
+
+type geofenceRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewGeofenceRepository(db *pgxpool.Pool) GeofenceRepository {
+	return &geofenceRepository{db: db}
+}
+
+const geofenceColumns = `id, name, type, latitude, longitude, radius, polygon, line, vehicle_ids,
+	ARRAY(SELECT gg.group_id FROM geofence_groups gg WHERE gg.geofence_id = geofences.id ORDER BY gg.group_id),
+	dwell_seconds, hysteresis_meters, min_points, min_duration_seconds, deviation_seconds,
+	schedule, tags, active, created_at, updated_at`
+
+func (r *geofenceRepository) FindActive(ctx context.Context) ([]*domain.Geofence, error) {
+	query := `
+		SELECT ` + geofenceColumns + `
+		FROM geofences
+		WHERE active = TRUE
+		ORDER BY name
+	`
+
+	return r.query(ctx, query)
+}
+
+func (r *geofenceRepository) FindAll(ctx context.Context) ([]*domain.Geofence, error) {
+	query := `
+		SELECT ` + geofenceColumns + `
+		FROM geofences
+		ORDER BY name
+	`
+
+	return r.query(ctx, query)
+}
+
+func (r *geofenceRepository) FindByID(ctx context.Context, id string) (*domain.Geofence, error) {
+	query := `
+		SELECT ` + geofenceColumns + `
+		FROM geofences
+		WHERE id = $1
+	`
+
+	geofence, err := scanGeofence(r.db.QueryRow(ctx, query, id))
+	if err == pgx.ErrNoRows {
+		return nil, fmt.Errorf("geofence not found")
+	}
+
+	return geofence, err
+}
+
+func (r *geofenceRepository) Create(ctx context.Context, geofence *domain.Geofence) error {
+	query := `
+		INSERT INTO geofences (id, name, type, latitude, longitude, radius, polygon, line, vehicle_ids,
+			dwell_seconds, hysteresis_meters, min_points, min_duration_seconds, deviation_seconds,
+			schedule, tags, active, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, NOW(), NOW())
+		RETURNING created_at, updated_at
+	`
+
+	geofence.ID = uuid.New().String()
+	args, err := geofenceArgs(geofence)
+	if err != nil {
+		return err
+	}
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	err = tx.QueryRow(ctx, query, args...).Scan(&geofence.CreatedAt, &geofence.UpdatedAt)
+	if err != nil {
+		return err
+	}
+
+	if err := replaceGeofenceGroups(ctx, tx, geofence); err != nil {
+		return err
+	}
+	if err := notifyGeofenceChange(ctx, tx, geofence.ID); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (r *geofenceRepository) Update(ctx context.Context, geofence *domain.Geofence) error {
+	query := `
+		UPDATE geofences
+		SET name = $2, type = $3, latitude = $4, longitude = $5, radius = $6, polygon = $7,
+			line = $8, vehicle_ids = $9, dwell_seconds = $10, hysteresis_meters = $11,
+			min_points = $12, min_duration_seconds = $13, deviation_seconds = $14,
+			schedule = $15, tags = $16, active = $17, updated_at = NOW()
+		WHERE id = $1
+		RETURNING created_at, updated_at
+	`
+
+	args, err := geofenceArgs(geofence)
+	if err != nil {
+		return err
+	}
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	err = tx.QueryRow(ctx, query, args...).Scan(&geofence.CreatedAt, &geofence.UpdatedAt)
+	if err == pgx.ErrNoRows {
+		return fmt.Errorf("geofence not found")
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := replaceGeofenceGroups(ctx, tx, geofence); err != nil {
+		return err
+	}
+	if err := notifyGeofenceChange(ctx, tx, geofence.ID); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (r *geofenceRepository) Delete(ctx context.Context, id string) error {
+	tag, err := r.db.Exec(ctx, `DELETE FROM geofences WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("geofence not found")
+	}
+
+	return notifyGeofenceChange(ctx, r.db, id)
+}
+
+func (r *geofenceRepository) Import(ctx context.Context, geofences []*domain.Geofence) error {
+	query := `
+		INSERT INTO geofences (id, name, type, latitude, longitude, radius, polygon, line, vehicle_ids,
+			dwell_seconds, hysteresis_meters, min_points, min_duration_seconds, deviation_seconds,
+			schedule, tags, active, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, NOW(), NOW())
+		ON CONFLICT (id) DO UPDATE SET
+			name = EXCLUDED.name, type = EXCLUDED.type, latitude = EXCLUDED.latitude,
+			longitude = EXCLUDED.longitude, radius = EXCLUDED.radius, polygon = EXCLUDED.polygon,
+			line = EXCLUDED.line, vehicle_ids = EXCLUDED.vehicle_ids, dwell_seconds = EXCLUDED.dwell_seconds,
+			hysteresis_meters = EXCLUDED.hysteresis_meters, min_points = EXCLUDED.min_points,
+			min_duration_seconds = EXCLUDED.min_duration_seconds, deviation_seconds = EXCLUDED.deviation_seconds,
+			schedule = EXCLUDED.schedule, tags = EXCLUDED.tags, active = EXCLUDED.active, updated_at = NOW()
+		RETURNING created_at, updated_at
+	`
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	for _, geofence := range geofences {
+		if geofence.ID == "" {
+			geofence.ID = uuid.New().String()
+		}
+		args, err := geofenceArgs(geofence)
+		if err != nil {
+			return err
+		}
+
+		err = tx.QueryRow(ctx, query, args...).Scan(&geofence.CreatedAt, &geofence.UpdatedAt)
+		if err != nil {
+			return err
+		}
+		if err := replaceGeofenceGroups(ctx, tx, geofence); err != nil {
+			return err
+		}
+	}
+
+	// One notification for the whole import, so listeners reload once
+	// instead of once per zone.
+	if len(geofences) > 0 {
+		if err := notifyGeofenceChange(ctx, tx, fmt.Sprintf("import:%d", len(geofences))); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+// Listen blocks until ctx is cancelled or the connection fails, calling
+// onChange with the zone ID whenever a geofence is created, updated or deleted.
+func (r *geofenceRepository) Listen(ctx context.Context, onChange func(id string)) error {
+	conn, err := r.db.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	if _, err := conn.Exec(ctx, "LISTEN "+geofenceChannel); err != nil {
+		return err
+	}
+
+	for {
+		notification, err := conn.Conn().WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+		onChange(notification.Payload)
+	}
+}
+
+func notifyGeofenceChange(ctx context.Context, db execer, payload string) error {
+	_, err := db.Exec(ctx, `SELECT pg_notify($1, $2)`, geofenceChannel, payload)
+	return err
+}
+
+func replaceGeofenceGroups(ctx context.Context, tx pgx.Tx, geofence *domain.Geofence) error {
+	if _, err := tx.Exec(ctx, `DELETE FROM geofence_groups WHERE geofence_id = $1`, geofence.ID); err != nil {
+		return err
+	}
+
+	for _, groupID := range geofence.GroupIDs {
+		query := `
+			INSERT INTO geofence_groups (geofence_id, group_id)
+			VALUES ($1, $2)
+			ON CONFLICT DO NOTHING
+		`
+		_, err := tx.Exec(ctx, query, geofence.ID, groupID)
+
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+			return fmt.Errorf("group not found")
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *geofenceRepository) query(ctx context.Context, query string, args ...interface{}) ([]*domain.Geofence, error) {
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var geofences []*domain.Geofence
+	for rows.Next() {
+		geofence, err := scanGeofence(rows)
+		if err != nil {
+			return nil, err
+		}
+		geofences = append(geofences, geofence)
+	}
+
+	return geofences, rows.Err()
+}
+
+func scanGeofence(row pgx.Row) (*domain.Geofence, error) {
+	var geofence domain.Geofence
+	var polygon, line, schedule []byte
+	err := row.Scan(
+		&geofence.ID,
+		&geofence.Name,
+		&geofence.Type,
+		&geofence.Latitude,
+		&geofence.Longitude,
+		&geofence.Radius,
+		&polygon,
+		&line,
+		&geofence.VehicleIDs,
+		&geofence.GroupIDs,
+		&geofence.DwellSeconds,
+		&geofence.HysteresisMeters,
+		&geofence.MinPoints,
+		&geofence.MinDurationSeconds,
+		&geofence.DeviationSeconds,
+		&schedule,
+		&geofence.Tags,
+		&geofence.Active,
+		&geofence.CreatedAt,
+		&geofence.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(polygon) > 0 {
+		if err := json.Unmarshal(polygon, &geofence.Polygon); err != nil {
+			return nil, err
+		}
+	}
+	if len(line) > 0 {
+		if err := json.Unmarshal(line, &geofence.Line); err != nil {
+			return nil, err
+		}
+	}
+	if len(schedule) > 0 {
+		if err := json.Unmarshal(schedule, &geofence.Schedule); err != nil {
+			return nil, err
+		}
+	}
+
+	return &geofence, nil
+}
+
+// geofenceArgs returns the column values in the order used by Create and Update.
+func geofenceArgs(geofence *domain.Geofence) ([]interface{}, error) {
+	var polygon, line, schedule []byte
+	var err error
+	if len(geofence.Polygon) > 0 {
+		if polygon, err = json.Marshal(geofence.Polygon); err != nil {
+			return nil, err
+		}
+	}
+	if len(geofence.Line) > 0 {
+		if line, err = json.Marshal(geofence.Line); err != nil {
+			return nil, err
+		}
+	}
+	if geofence.Schedule != nil {
+		if schedule, err = json.Marshal(geofence.Schedule); err != nil {
+			return nil, err
+		}
+	}
+
+	return []interface{}{
+		geofence.ID,
+		geofence.Name,
+		geofence.Type,
+		geofence.Latitude,
+		geofence.Longitude,
+		geofence.Radius,
+		polygon,
+		line,
+		nonNil(geofence.VehicleIDs),
+		geofence.DwellSeconds,
+		geofence.HysteresisMeters,
+		geofence.MinPoints,
+		geofence.MinDurationSeconds,
+		geofence.DeviationSeconds,
+		schedule,
+		nonNil(geofence.Tags),
+		geofence.Active,
+	}, nil
+}
+
+// nonNil keeps NOT NULL array columns from receiving NULL.
+func nonNil(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
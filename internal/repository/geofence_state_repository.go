@@ -0,0 +1,149 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/fahri/go-tije/internal/domain"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type GeofenceStateRepository interface {
+	FindByVehicle(ctx context.Context, vehicleID string) ([]*domain.GeofenceState, error)
+	Save(ctx context.Context, state *domain.GeofenceState) error
+	// LastEvaluated returns the timestamp of the newest location evaluated
+	// for the vehicle, or 0 if none was.
+	LastEvaluated(ctx context.Context, vehicleID string) (int64, error)
+	SetLastEvaluated(ctx context.Context, vehicleID string, timestamp int64) error
+	// WithVehicleLock runs fn in a transaction holding an advisory lock on
+	// vehicleID, so replicas evaluating the same vehicle take turns. The
+	// repository passed to fn reads and writes inside that transaction, which
+	// commits when fn returns nil.
+	WithVehicleLock(ctx context.Context, vehicleID string, fn func(states GeofenceStateRepository) error) error
+}
+
+type querier interface {
+	execer
+	Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error)
+}
+
+type geofenceStateRepository struct {
+	db   querier
+	pool *pgxpool.Pool
+}
+
+func NewGeofenceStateRepository(db *pgxpool.Pool) GeofenceStateRepository {
+	return &geofenceStateRepository{db: db, pool: db}
+}
+
+func (r *geofenceStateRepository) WithVehicleLock(ctx context.Context, vehicleID string, fn func(states GeofenceStateRepository) error) error {
+	if r.pool == nil {
+		// Already inside a locked transaction.
+		return fn(r)
+	}
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtext($1))`, vehicleID); err != nil {
+		return err
+	}
+	if err := fn(&geofenceStateRepository{db: tx}); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (r *geofenceStateRepository) FindByVehicle(ctx context.Context, vehicleID string) ([]*domain.GeofenceState, error) {
+	query := `
+		SELECT vehicle_id, geofence_id, inside, entered_at, dwell_notified, pending_count, pending_since, updated_at
+		FROM geofence_states
+		WHERE vehicle_id = $1
+	`
+
+	rows, err := r.db.Query(ctx, query, vehicleID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var states []*domain.GeofenceState
+	for rows.Next() {
+		var state domain.GeofenceState
+		err := rows.Scan(
+			&state.VehicleID,
+			&state.GeofenceID,
+			&state.Inside,
+			&state.EnteredAt,
+			&state.DwellNotified,
+			&state.PendingCount,
+			&state.PendingSince,
+			&state.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		states = append(states, &state)
+	}
+
+	return states, rows.Err()
+}
+
+func (r *geofenceStateRepository) Save(ctx context.Context, state *domain.GeofenceState) error {
+	query := `
+		INSERT INTO geofence_states (vehicle_id, geofence_id, inside, entered_at, dwell_notified, pending_count, pending_since, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NOW())
+		ON CONFLICT (vehicle_id, geofence_id) DO UPDATE SET
+			inside = EXCLUDED.inside,
+			entered_at = EXCLUDED.entered_at,
+			dwell_notified = EXCLUDED.dwell_notified,
+			pending_count = EXCLUDED.pending_count,
+			pending_since = EXCLUDED.pending_since,
+			updated_at = NOW()
+	`
+
+	_, err := r.db.Exec(ctx, query,
+		state.VehicleID,
+		state.GeofenceID,
+		state.Inside,
+		state.EnteredAt,
+		state.DwellNotified,
+		state.PendingCount,
+		state.PendingSince,
+	)
+
+	return err
+}
+
+func (r *geofenceStateRepository) LastEvaluated(ctx context.Context, vehicleID string) (int64, error) {
+	rows, err := r.db.Query(ctx, `SELECT last_timestamp FROM geofence_evaluations WHERE vehicle_id = $1`, vehicleID)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	var timestamp int64
+	if rows.Next() {
+		if err := rows.Scan(&timestamp); err != nil {
+			return 0, err
+		}
+	}
+	return timestamp, rows.Err()
+}
+
+func (r *geofenceStateRepository) SetLastEvaluated(ctx context.Context, vehicleID string, timestamp int64) error {
+	query := `
+		INSERT INTO geofence_evaluations (vehicle_id, last_timestamp, updated_at)
+		VALUES ($1, $2, NOW())
+		ON CONFLICT (vehicle_id) DO UPDATE SET
+			last_timestamp = GREATEST(geofence_evaluations.last_timestamp, EXCLUDED.last_timestamp),
+			updated_at = NOW()
+	`
+
+	_, err := r.db.Exec(ctx, query, vehicleID, timestamp)
+	return err
+}
@@ -0,0 +1,225 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/fahri/go-tije/internal/domain"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type GroupRepository interface {
+	FindAll(ctx context.Context) ([]*domain.VehicleGroup, error)
+	FindByID(ctx context.Context, id string) (*domain.VehicleGroup, error)
+	Create(ctx context.Context, group *domain.VehicleGroup) error
+	Update(ctx context.Context, group *domain.VehicleGroup) error
+	Delete(ctx context.Context, id string) error
+	AddVehicle(ctx context.Context, groupID, vehicleID string) error
+	RemoveVehicle(ctx context.Context, groupID, vehicleID string) error
+}
+
+type groupRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewGroupRepository(db *pgxpool.Pool) GroupRepository {
+	return &groupRepository{db: db}
+}
+
+const groupColumns = `g.id, g.name, g.type,
+	ARRAY(SELECT m.vehicle_id FROM vehicle_group_members m WHERE m.group_id = g.id ORDER BY m.vehicle_id),
+	g.created_at, g.updated_at`
+
+func (r *groupRepository) FindAll(ctx context.Context) ([]*domain.VehicleGroup, error) {
+	query := `
+		SELECT ` + groupColumns + `
+		FROM vehicle_groups g
+		ORDER BY g.name
+	`
+
+	rows, err := r.db.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var groups []*domain.VehicleGroup
+	for rows.Next() {
+		group, err := scanGroup(rows)
+		if err != nil {
+			return nil, err
+		}
+		groups = append(groups, group)
+	}
+
+	return groups, rows.Err()
+}
+
+func (r *groupRepository) FindByID(ctx context.Context, id string) (*domain.VehicleGroup, error) {
+	query := `
+		SELECT ` + groupColumns + `
+		FROM vehicle_groups g
+		WHERE g.id = $1
+	`
+
+	group, err := scanGroup(r.db.QueryRow(ctx, query, id))
+	if err == pgx.ErrNoRows {
+		return nil, fmt.Errorf("group not found")
+	}
+
+	return group, err
+}
+
+func (r *groupRepository) Create(ctx context.Context, group *domain.VehicleGroup) error {
+	query := `
+		INSERT INTO vehicle_groups (id, name, type, created_at, updated_at)
+		VALUES ($1, $2, $3, NOW(), NOW())
+		RETURNING created_at, updated_at
+	`
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	group.ID = uuid.New().String()
+	err = tx.QueryRow(ctx, query, group.ID, group.Name, group.Type).Scan(&group.CreatedAt, &group.UpdatedAt)
+	if err != nil {
+		return err
+	}
+
+	if err := replaceMembers(ctx, tx, group); err != nil {
+		return err
+	}
+	if err := notifyGeofenceChange(ctx, tx, "group:"+group.ID); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (r *groupRepository) Update(ctx context.Context, group *domain.VehicleGroup) error {
+	query := `
+		UPDATE vehicle_groups
+		SET name = $2, type = $3, updated_at = NOW()
+		WHERE id = $1
+		RETURNING created_at, updated_at
+	`
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	err = tx.QueryRow(ctx, query, group.ID, group.Name, group.Type).Scan(&group.CreatedAt, &group.UpdatedAt)
+	if err == pgx.ErrNoRows {
+		return fmt.Errorf("group not found")
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := replaceMembers(ctx, tx, group); err != nil {
+		return err
+	}
+	if err := notifyGeofenceChange(ctx, tx, "group:"+group.ID); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// Delete refuses to remove a group that geofences are still assigned to, as
+// those zones would silently start applying to every vehicle.
+func (r *groupRepository) Delete(ctx context.Context, id string) error {
+	tag, err := r.db.Exec(ctx, `DELETE FROM vehicle_groups WHERE id = $1`, id)
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+		return fmt.Errorf("group is assigned to geofences")
+	}
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("group not found")
+	}
+
+	return notifyGeofenceChange(ctx, r.db, "group:"+id)
+}
+
+func (r *groupRepository) AddVehicle(ctx context.Context, groupID, vehicleID string) error {
+	query := `
+		INSERT INTO vehicle_group_members (group_id, vehicle_id)
+		VALUES ($1, $2)
+		ON CONFLICT DO NOTHING
+	`
+
+	if _, err := r.FindByID(ctx, groupID); err != nil {
+		return err
+	}
+	if _, err := r.db.Exec(ctx, query, groupID, vehicleID); err != nil {
+		return err
+	}
+
+	return notifyGeofenceChange(ctx, r.db, "group:"+groupID)
+}
+
+func (r *groupRepository) RemoveVehicle(ctx context.Context, groupID, vehicleID string) error {
+	query := `
+		DELETE FROM vehicle_group_members
+		WHERE group_id = $1 AND vehicle_id = $2
+	`
+
+	tag, err := r.db.Exec(ctx, query, groupID, vehicleID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("group member not found")
+	}
+
+	return notifyGeofenceChange(ctx, r.db, "group:"+groupID)
+}
+
+func replaceMembers(ctx context.Context, tx pgx.Tx, group *domain.VehicleGroup) error {
+	if _, err := tx.Exec(ctx, `DELETE FROM vehicle_group_members WHERE group_id = $1`, group.ID); err != nil {
+		return err
+	}
+
+	for _, vehicleID := range group.VehicleIDs {
+		query := `
+			INSERT INTO vehicle_group_members (group_id, vehicle_id)
+			VALUES ($1, $2)
+			ON CONFLICT DO NOTHING
+		`
+		if _, err := tx.Exec(ctx, query, group.ID, vehicleID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func scanGroup(row pgx.Row) (*domain.VehicleGroup, error) {
+	var group domain.VehicleGroup
+	err := row.Scan(
+		&group.ID,
+		&group.Name,
+		&group.Type,
+		&group.VehicleIDs,
+		&group.CreatedAt,
+		&group.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &group, nil
+}
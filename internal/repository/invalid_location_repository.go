@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/fahri/go-tije/internal/domain"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// InvalidLocationRepository is the sink for rejected location messages.
+type InvalidLocationRepository interface {
+	Save(ctx context.Context, invalid *domain.InvalidLocation) error
+	CountByReason(ctx context.Context, start, end int64) (map[domain.RejectReason]int64, error)
+	FindRecent(ctx context.Context, reason domain.RejectReason, limit int) ([]*domain.InvalidLocation, error)
+}
+
+type invalidLocationRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewInvalidLocationRepository(db *pgxpool.Pool) InvalidLocationRepository {
+	return &invalidLocationRepository{db: db}
+}
+
+func (r *invalidLocationRepository) Save(ctx context.Context, invalid *domain.InvalidLocation) error {
+	query := `
+		INSERT INTO invalid_locations (id, vehicle_id, reason, message, payload, created_at)
+		VALUES ($1, $2, $3, $4, $5, NOW())
+		RETURNING created_at
+	`
+
+	invalid.ID = uuid.New().String()
+	return r.db.QueryRow(ctx, query,
+		invalid.ID,
+		invalid.VehicleID,
+		invalid.Reason,
+		invalid.Message,
+		invalid.Payload,
+	).Scan(&invalid.CreatedAt)
+}
+
+// CountByReason counts rejections received between start and end (Unix
+// seconds, inclusive).
+func (r *invalidLocationRepository) CountByReason(ctx context.Context, start, end int64) (map[domain.RejectReason]int64, error) {
+	query := `
+		SELECT reason, COUNT(*)
+		FROM invalid_locations
+		WHERE created_at BETWEEN to_timestamp($1) AND to_timestamp($2)
+		GROUP BY reason
+	`
+
+	rows, err := r.db.Query(ctx, query, start, end)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[domain.RejectReason]int64)
+	for rows.Next() {
+		var reason domain.RejectReason
+		var count int64
+		if err := rows.Scan(&reason, &count); err != nil {
+			return nil, err
+		}
+		counts[reason] = count
+	}
+
+	return counts, rows.Err()
+}
+
+// FindRecent returns the latest rejections, newest first. An empty reason
+// matches every reason.
+func (r *invalidLocationRepository) FindRecent(ctx context.Context, reason domain.RejectReason, limit int) ([]*domain.InvalidLocation, error) {
+	query := `
+		SELECT id, vehicle_id, reason, message, payload, created_at
+		FROM invalid_locations
+		WHERE $1 = '' OR reason = $1
+		ORDER BY created_at DESC
+		LIMIT $2
+	`
+
+	rows, err := r.db.Query(ctx, query, string(reason), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var invalids []*domain.InvalidLocation
+	for rows.Next() {
+		var invalid domain.InvalidLocation
+		err := rows.Scan(
+			&invalid.ID,
+			&invalid.VehicleID,
+			&invalid.Reason,
+			&invalid.Message,
+			&invalid.Payload,
+			&invalid.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		invalids = append(invalids, &invalid)
+	}
+
+	return invalids, rows.Err()
+}
@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/fahri/go-tije/internal/domain"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type PresenceRepository interface {
+	Save(ctx context.Context, vehicleID, status string, timestamp int64, seen bool) error
+	Find(ctx context.Context, vehicleID string) (*domain.VehiclePresence, error)
+}
+
+type presenceRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewPresenceRepository(db *pgxpool.Pool) PresenceRepository {
+	return &presenceRepository{db: db}
+}
+
+// Save records a status reported at timestamp (Unix seconds). A report older
+// than the stored one is ignored, and since only moves when the status
+// changes. seen marks a report sent by the tracker itself rather than by the
+// broker on its behalf, which also advances the last-seen time.
+func (r *presenceRepository) Save(ctx context.Context, vehicleID, status string, timestamp int64, seen bool) error {
+	query := `
+		INSERT INTO vehicle_presence AS p (vehicle_id, status, changed_at, reported_at, last_seen_at)
+		VALUES ($1, $2, to_timestamp($3), to_timestamp($3), CASE WHEN $4 THEN to_timestamp($3) END)
+		ON CONFLICT (vehicle_id) DO UPDATE SET
+			status = EXCLUDED.status,
+			changed_at = CASE WHEN p.status = EXCLUDED.status THEN p.changed_at ELSE EXCLUDED.changed_at END,
+			reported_at = EXCLUDED.reported_at,
+			last_seen_at = GREATEST(p.last_seen_at, EXCLUDED.last_seen_at)
+		WHERE EXCLUDED.reported_at >= p.reported_at
+	`
+
+	_, err := r.db.Exec(ctx, query, vehicleID, status, timestamp, seen)
+	return err
+}
+
+func (r *presenceRepository) Find(ctx context.Context, vehicleID string) (*domain.VehiclePresence, error) {
+	query := `
+		SELECT vehicle_id, status, changed_at, last_seen_at
+		FROM vehicle_presence
+		WHERE vehicle_id = $1
+	`
+
+	var presence domain.VehiclePresence
+	err := r.db.QueryRow(ctx, query, vehicleID).Scan(
+		&presence.VehicleID,
+		&presence.Status,
+		&presence.Since,
+		&presence.LastSeen,
+	)
+	if err == pgx.ErrNoRows {
+		return nil, fmt.Errorf("presence not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &presence, nil
+}
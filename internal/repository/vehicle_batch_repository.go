@@ -0,0 +1,300 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/fahri/go-tije/internal/config"
+	"github.com/fahri/go-tije/internal/domain"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	batchWriteTimeout = 30 * time.Second
+	batchMaxAttempts  = 5
+)
+
+var errRepositoryClosed = errors.New("vehicle repository closed")
+
+// BatchVehicleRepository is a VehicleRepository that writes locations with
+// pipelined INSERTs. Save blocks while the buffer is full, pushing back on the
+// caller instead of growing without bound.
+//
+// Save returns as soon as a backfill location is queued; those are written
+// once BatchSize rows are waiting or BatchFlushInterval has passed. A live
+// location goes on to geofence evaluation, so Save waits until its batch is
+// written and reports ErrDuplicateLocation when the row was already stored.
+// Queueing a live location flushes right away, together with everything
+// queued meanwhile, so concurrent callers still share a round trip.
+type BatchVehicleRepository interface {
+	VehicleRepository
+	// Close stops accepting locations and waits until everything queued has
+	// been written or ctx is done.
+	Close(ctx context.Context) error
+}
+
+// queuedLocation is a location waiting to be written. result is nil for
+// backfill locations, whose caller does not wait for the outcome.
+type queuedLocation struct {
+	location *domain.VehicleLocation
+	result   chan error
+}
+
+func (q *queuedLocation) report(err error) {
+	if q.result != nil {
+		q.result <- err
+	}
+}
+
+type batchVehicleRepository struct {
+	*vehicleRepository
+	invalid  InvalidLocationRepository
+	queue    chan *queuedLocation
+	size     int
+	interval time.Duration
+	done     chan struct{}
+
+	mu     sync.RWMutex
+	closed bool
+}
+
+func NewBatchVehicleRepository(db *pgxpool.Pool, cfg *config.DatabaseConfig) BatchVehicleRepository {
+	r := &batchVehicleRepository{
+		vehicleRepository: &vehicleRepository{db: db},
+		invalid:           NewInvalidLocationRepository(db),
+		queue:             make(chan *queuedLocation, max(cfg.BatchBuffer, 1)),
+		size:              max(cfg.BatchSize, 1),
+		interval:          cfg.BatchFlushInterval,
+		done:              make(chan struct{}),
+	}
+	if r.interval <= 0 {
+		r.interval = time.Second
+	}
+
+	go r.run()
+	return r
+}
+
+func (r *batchVehicleRepository) Save(ctx context.Context, location *domain.VehicleLocation) error {
+	queued := &queuedLocation{location: location}
+	if !location.Backfill {
+		queued.result = make(chan error, 1)
+	}
+	location.ID = uuid.New().String()
+
+	if err := r.enqueue(ctx, queued); err != nil {
+		return err
+	}
+	if queued.result == nil {
+		return nil
+	}
+
+	select {
+	case err := <-queued.result:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (r *batchVehicleRepository) enqueue(ctx context.Context, queued *queuedLocation) error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if r.closed {
+		return errRepositoryClosed
+	}
+
+	select {
+	case r.queue <- queued:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (r *batchVehicleRepository) Close(ctx context.Context) error {
+	r.mu.Lock()
+	if !r.closed {
+		r.closed = true
+		close(r.queue)
+	}
+	r.mu.Unlock()
+
+	select {
+	case <-r.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (r *batchVehicleRepository) run() {
+	defer close(r.done)
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	pending := make([]*queuedLocation, 0, r.size)
+	for {
+		select {
+		case queued, ok := <-r.queue:
+			if !ok {
+				r.flush(pending)
+				return
+			}
+			pending = append(pending, queued)
+			if queued.result != nil {
+				// A caller is waiting: take whatever else is already queued
+				// and write it now.
+				pending = r.drain(pending)
+			}
+			if queued.result != nil || len(pending) >= r.size {
+				r.flush(pending)
+				pending = pending[:0]
+			}
+		case <-ticker.C:
+			r.flush(pending)
+			pending = pending[:0]
+		}
+	}
+}
+
+// drain appends queued locations to pending, without waiting, until the batch
+// is full or the queue is empty. A closed queue is left for run to notice.
+func (r *batchVehicleRepository) drain(pending []*queuedLocation) []*queuedLocation {
+	for len(pending) < r.size {
+		select {
+		case queued, ok := <-r.queue:
+			if !ok {
+				return pending
+			}
+			pending = append(pending, queued)
+		default:
+			return pending
+		}
+	}
+	return pending
+}
+
+// flush writes locations in a single pipelined round trip, retrying with
+// backoff so a short database outage does not lose the batch. New locations
+// keep queueing meanwhile until the buffer is full. The pipeline shares one
+// implicit transaction, so a row the database rejects fails the whole batch;
+// then the rows are written one by one and only the rejected ones are dropped.
+// Every waiting caller is told how its row fared.
+func (r *batchVehicleRepository) flush(pending []*queuedLocation) {
+	if len(pending) == 0 {
+		return
+	}
+
+	backoff := 500 * time.Millisecond
+	for attempt := 1; ; attempt++ {
+		inserted, err := r.write(pending)
+		if err == nil {
+			for i, queued := range pending {
+				queued.report(insertResult(inserted[i]))
+			}
+			return
+		}
+		if !isTransient(err) {
+			log.Printf("Failed to write %d locations, writing them one by one: %v", len(pending), err)
+			r.writeEach(pending)
+			return
+		}
+		if attempt == batchMaxAttempts {
+			log.Printf("Dropping %d locations after %d failed writes: %v", len(pending), attempt, err)
+			for _, queued := range pending {
+				queued.report(err)
+			}
+			return
+		}
+
+		log.Printf("Failed to write %d locations (attempt %d): %v", len(pending), attempt, err)
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// write reports, per location, whether its row was inserted or skipped as a
+// duplicate.
+func (r *batchVehicleRepository) write(pending []*queuedLocation) ([]bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), batchWriteTimeout)
+	defer cancel()
+
+	batch := &pgx.Batch{}
+	for _, queued := range pending {
+		batch.Queue(insertLocationQuery, locationArgs(queued.location)...)
+	}
+
+	results := r.db.SendBatch(ctx, batch)
+	inserted := make([]bool, len(pending))
+	for i := range pending {
+		tag, err := results.Exec()
+		if err != nil {
+			results.Close()
+			return nil, err
+		}
+		inserted[i] = tag.RowsAffected() > 0
+	}
+
+	return inserted, results.Close()
+}
+
+// writeEach inserts locations separately and records the ones the database
+// rejects as invalid locations.
+func (r *batchVehicleRepository) writeEach(pending []*queuedLocation) {
+	ctx, cancel := context.WithTimeout(context.Background(), batchWriteTimeout)
+	defer cancel()
+
+	for _, queued := range pending {
+		location := queued.location
+		tag, err := r.db.Exec(ctx, insertLocationQuery, locationArgs(location)...)
+		if err == nil {
+			queued.report(insertResult(tag.RowsAffected() > 0))
+			continue
+		}
+		queued.report(err)
+
+		log.Printf("Dropping location of %s at %d: %v", location.VehicleID, location.Timestamp, err)
+		payload, _ := json.Marshal(location)
+		invalid := &domain.InvalidLocation{
+			VehicleID: location.VehicleID,
+			Reason:    domain.RejectStorageFailed,
+			Message:   err.Error(),
+			Payload:   payload,
+		}
+		if err := r.invalid.Save(ctx, invalid); err != nil {
+			log.Printf("Failed to record dropped location: %v", err)
+		}
+	}
+}
+
+func insertResult(inserted bool) error {
+	if inserted {
+		return nil
+	}
+	return domain.ErrDuplicateLocation
+}
+
+// isTransient reports whether err may go away on retry: connection and
+// network failures, serialization conflicts, exhausted resources or a server
+// shutting down. Other server errors come from the rows themselves.
+func isTransient(err error) bool {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return true
+	}
+
+	switch pgErr.Code[:2] {
+	case "08", "40", "53", "57":
+		return true
+	}
+	return false
+}
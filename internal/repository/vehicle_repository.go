@@ -14,6 +14,7 @@ type VehicleRepository interface {
 	Save(ctx context.Context, location *domain.VehicleLocation) error
 	FindLatest(ctx context.Context, vehicleID string) (*domain.VehicleLocation, error)
 	FindHistory(ctx context.Context, vehicleID string, start, end int64) ([]*domain.VehicleLocation, error)
+	MarkBackfill(ctx context.Context, id string) error
 }
 
 type vehicleRepository struct {
@@ -24,53 +25,58 @@ func NewVehicleRepository(db *pgxpool.Pool) VehicleRepository {
 	return &vehicleRepository{db: db}
 }
 
+// insertLocationQuery skips a location whose (vehicle_id, message_id), or
+// (vehicle_id, timestamp) when it has no message ID, is already stored.
+const insertLocationQuery = `
+	INSERT INTO vehicle_locations (id, vehicle_id, latitude, longitude, timestamp, message_id, backfill,
+		speed, heading, altitude, hdop, accuracy, satellites, fix_quality, ignition, odometer, battery_voltage, created_at)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, NOW())
+	ON CONFLICT DO NOTHING
+`
+
+const locationColumns = `id, vehicle_id, latitude, longitude, timestamp, COALESCE(message_id, ''), backfill,
+	speed, heading, altitude, hdop, accuracy, satellites, fix_quality, ignition, odometer, battery_voltage, created_at`
+
 func (r *vehicleRepository) Save(ctx context.Context, location *domain.VehicleLocation) error {
-	query := `
-		INSERT INTO vehicle_locations (id, vehicle_id, latitude, longitude, timestamp, created_at)
-		VALUES ($1, $2, $3, $4, $5, NOW())
-	`
-	
 	location.ID = uuid.New().String()
-	_, err := r.db.Exec(ctx, query,
-		location.ID,
-		location.VehicleID,
-		location.Latitude,
-		location.Longitude,
-		location.Timestamp,
-	)
+	tag, err := r.db.Exec(ctx, insertLocationQuery, locationArgs(location)...)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return domain.ErrDuplicateLocation
+	}
 	
+	return nil
+}
+
+// MarkBackfill flags a stored location as backfill once it turns out to be
+// older than a location already evaluated.
+func (r *vehicleRepository) MarkBackfill(ctx context.Context, id string) error {
+	_, err := r.db.Exec(ctx, `UPDATE vehicle_locations SET backfill = TRUE WHERE id = $1`, id)
 	return err
 }
 
 func (r *vehicleRepository) FindLatest(ctx context.Context, vehicleID string) (*domain.VehicleLocation, error) {
 	query := `
-		SELECT id, vehicle_id, latitude, longitude, timestamp, created_at
+		SELECT ` + locationColumns + `
 		FROM vehicle_locations
 		WHERE vehicle_id = $1
 		ORDER BY timestamp DESC
 		LIMIT 1
 	`
 	
-	var location domain.VehicleLocation
-	err := r.db.QueryRow(ctx, query, vehicleID).Scan(
-		&location.ID,
-		&location.VehicleID,
-		&location.Latitude,
-		&location.Longitude,
-		&location.Timestamp,
-		&location.CreatedAt,
-	)
-	
+	location, err := scanLocation(r.db.QueryRow(ctx, query, vehicleID))
 	if err == pgx.ErrNoRows {
 		return nil, fmt.Errorf("vehicle not found")
 	}
 	
-	return &location, err
+	return location, err
 }
 
 func (r *vehicleRepository) FindHistory(ctx context.Context, vehicleID string, start, end int64) ([]*domain.VehicleLocation, error) {
 	query := `
-		SELECT id, vehicle_id, latitude, longitude, timestamp, created_at
+		SELECT ` + locationColumns + `
 		FROM vehicle_locations
 		WHERE vehicle_id = $1 AND timestamp BETWEEN $2 AND $3
 		ORDER BY timestamp DESC
@@ -84,20 +90,70 @@ func (r *vehicleRepository) FindHistory(ctx context.Context, vehicleID string, s
 	
 	var locations []*domain.VehicleLocation
 	for rows.Next() {
-		var location domain.VehicleLocation
-		err := rows.Scan(
-			&location.ID,
-			&location.VehicleID,
-			&location.Latitude,
-			&location.Longitude,
-			&location.Timestamp,
-			&location.CreatedAt,
-		)
+		location, err := scanLocation(rows)
 		if err != nil {
 			return nil, err
 		}
-		locations = append(locations, &location)
+		locations = append(locations, location)
 	}
 	
 	return locations, nil
+}
+
+func scanLocation(row pgx.Row) (*domain.VehicleLocation, error) {
+	var location domain.VehicleLocation
+	err := row.Scan(
+		&location.ID,
+		&location.VehicleID,
+		&location.Latitude,
+		&location.Longitude,
+		&location.Timestamp,
+		&location.MessageID,
+		&location.Backfill,
+		&location.Speed,
+		&location.Heading,
+		&location.Altitude,
+		&location.HDOP,
+		&location.Accuracy,
+		&location.Satellites,
+		&location.FixQuality,
+		&location.Ignition,
+		&location.Odometer,
+		&location.BatteryVoltage,
+		&location.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	
+	return &location, nil
+}
+
+func locationArgs(location *domain.VehicleLocation) []interface{} {
+	return []interface{}{
+		location.ID,
+		location.VehicleID,
+		location.Latitude,
+		location.Longitude,
+		location.Timestamp,
+		nullString(location.MessageID),
+		location.Backfill,
+		location.Speed,
+		location.Heading,
+		location.Altitude,
+		location.HDOP,
+		location.Accuracy,
+		location.Satellites,
+		location.FixQuality,
+		location.Ignition,
+		location.Odometer,
+		location.BatteryVoltage,
+	}
+}
+
+func nullString(value string) *string {
+	if value == "" {
+		return nil
+	}
+	return &value
 }
\ No newline at end of file
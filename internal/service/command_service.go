@@ -0,0 +1,140 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/fahri/go-tije/internal/config"
+	"github.com/fahri/go-tije/internal/domain"
+	"github.com/fahri/go-tije/internal/repository"
+	mqttclient "github.com/fahri/go-tije/pkg/mqtt"
+)
+
+type CommandService interface {
+	Send(ctx context.Context, command *domain.VehicleCommand, timeout time.Duration) error
+	Get(ctx context.Context, vehicleID, id string) (*domain.VehicleCommand, error)
+	List(ctx context.Context, vehicleID string, limit int) ([]*domain.VehicleCommand, error)
+	ProcessAck(ctx context.Context, vehicleID string, payload []byte) error
+	Watch(ctx context.Context)
+}
+
+type commandService struct {
+	repo   repository.CommandRepository
+	mqtt   *mqttclient.Client
+	config *config.CommandConfig
+}
+
+func NewCommandService(repo repository.CommandRepository, mqtt *mqttclient.Client, cfg *config.CommandConfig) CommandService {
+	return &commandService{
+		repo:   repo,
+		mqtt:   mqtt,
+		config: cfg,
+	}
+}
+
+// Send stores the command as pending and publishes it to the vehicle. A zero
+// timeout uses the configured default. The payload carries the expiry stored
+// with the command. When publishing fails or times out, for example while the
+// broker connection is being re-established, the command is marked failed and
+// the error returned.
+func (s *commandService) Send(ctx context.Context, command *domain.VehicleCommand, timeout time.Duration) error {
+	if command.Type == domain.CommandTypeBuzzer && command.Params.DurationSeconds == 0 {
+		command.Params.DurationSeconds = 5
+	}
+	if err := command.Validate(); err != nil {
+		return err
+	}
+	if timeout <= 0 {
+		timeout = s.config.Timeout
+	}
+
+	if err := s.repo.Create(ctx, command, timeout); err != nil {
+		return err
+	}
+
+	payload, err := json.Marshal(&domain.CommandMessage{
+		ID:        command.ID,
+		Type:      command.Type,
+		Params:    command.Params,
+		ExpiresAt: command.ExpiresAt.Unix(),
+	})
+	if err != nil {
+		return err
+	}
+
+	topic := fmt.Sprintf("/fleet/vehicle/%s/command", command.VehicleID)
+	if err := s.mqtt.Publish(topic, payload); err != nil {
+		command.Status = domain.CommandStatusFailed
+		command.Error = "failed to publish command: " + err.Error()
+		if resolveErr := s.repo.Resolve(ctx, command.VehicleID, command.ID, command.Status, command.Error); resolveErr != nil {
+			log.Printf("Failed to mark command %s as failed: %v", command.ID, resolveErr)
+		}
+		return err
+	}
+
+	return nil
+}
+
+func (s *commandService) Get(ctx context.Context, vehicleID, id string) (*domain.VehicleCommand, error) {
+	return s.repo.FindByID(ctx, vehicleID, id)
+}
+
+func (s *commandService) List(ctx context.Context, vehicleID string, limit int) ([]*domain.VehicleCommand, error) {
+	return s.repo.FindByVehicle(ctx, vehicleID, limit)
+}
+
+// ProcessAck resolves the pending command a tracker replied to on
+// /fleet/vehicle/{id}/command/ack. Replies to unknown, resolved or expired
+// commands are ignored with an error.
+func (s *commandService) ProcessAck(ctx context.Context, vehicleID string, payload []byte) error {
+	var ack domain.CommandAck
+	if err := json.Unmarshal(payload, &ack); err != nil {
+		return fmt.Errorf("invalid command ack: %v", err)
+	}
+	if ack.ID == "" {
+		return fmt.Errorf("command ack without id")
+	}
+
+	var status string
+	switch ack.Status {
+	case "ok":
+		status = domain.CommandStatusAcked
+	case "error":
+		status = domain.CommandStatusFailed
+		if ack.Error == "" {
+			ack.Error = "rejected by device"
+		}
+	default:
+		return fmt.Errorf("command ack status must be ok or error, got %q", ack.Status)
+	}
+
+	if err := s.repo.Resolve(ctx, vehicleID, ack.ID, status, ack.Error); err != nil {
+		return fmt.Errorf("command %s: %v", ack.ID, err)
+	}
+
+	return nil
+}
+
+// Watch marks expired pending commands as timed out every SweepInterval
+// until ctx is cancelled.
+func (s *commandService) Watch(ctx context.Context) {
+	ticker := time.NewTicker(s.config.SweepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			expired, err := s.repo.ExpirePending(ctx)
+			if err != nil {
+				log.Printf("Failed to expire pending commands: %v", err)
+			} else if expired > 0 {
+				log.Printf("Marked %d commands as timed out", expired)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/fahri/go-tije/internal/domain"
+	"github.com/fahri/go-tije/internal/repository"
+)
+
+type CredentialService interface {
+	List(ctx context.Context) ([]*domain.IngestCredential, error)
+	Create(ctx context.Context, credential *domain.IngestCredential) error
+	Delete(ctx context.Context, id string) error
+	Authenticate(ctx context.Context, token string) (*domain.IngestCredential, error)
+}
+
+type credentialService struct {
+	repo repository.CredentialRepository
+}
+
+func NewCredentialService(repo repository.CredentialRepository) CredentialService {
+	return &credentialService{
+		repo: repo,
+	}
+}
+
+func (s *credentialService) List(ctx context.Context) ([]*domain.IngestCredential, error) {
+	return s.repo.FindAll(ctx)
+}
+
+// Create issues a random token for the credential. The token is set on
+// credential and cannot be retrieved again.
+func (s *credentialService) Create(ctx context.Context, credential *domain.IngestCredential) error {
+	if err := credential.Validate(); err != nil {
+		return err
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return err
+	}
+	token := hex.EncodeToString(secret)
+
+	if err := s.repo.Create(ctx, credential, hashToken(token)); err != nil {
+		return err
+	}
+
+	credential.Token = token
+	return nil
+}
+
+func (s *credentialService) Delete(ctx context.Context, id string) error {
+	return s.repo.Delete(ctx, id)
+}
+
+func (s *credentialService) Authenticate(ctx context.Context, token string) (*domain.IngestCredential, error) {
+	if token == "" {
+		return nil, fmt.Errorf("invalid credential")
+	}
+	return s.repo.Authenticate(ctx, hashToken(token))
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
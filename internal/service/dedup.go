@@ -0,0 +1,91 @@
+package service
+
+import (
+	"strconv"
+	"sync"
+
+	"github.com/fahri/go-tije/internal/domain"
+)
+
+// deduplicator remembers the last window location keys of every vehicle so
+// redelivered and resent messages can be dropped before they reach storage
+// or geofence evaluation. The unique indexes on vehicle_locations catch the
+// duplicates that arrive after a key has been forgotten.
+type deduplicator struct {
+	mu       sync.Mutex
+	window   int
+	vehicles map[string]*recentKeys
+}
+
+type recentKeys struct {
+	keys []string
+	next int
+	set  map[string]struct{}
+}
+
+func newDeduplicator(window int) *deduplicator {
+	return &deduplicator{
+		window:   max(window, 1),
+		vehicles: make(map[string]*recentKeys),
+	}
+}
+
+// dedupKey identifies a location: the device message ID when present,
+// otherwise its timestamp.
+func dedupKey(message *domain.LocationMessage) string {
+	if message.MessageID != "" {
+		return "m:" + message.MessageID
+	}
+	return "t:" + strconv.FormatInt(message.Timestamp, 10)
+}
+
+// Seen records key for the vehicle and reports whether it was already known.
+func (d *deduplicator) Seen(vehicleID, key string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	recent := d.vehicles[vehicleID]
+	if recent == nil {
+		recent = &recentKeys{
+			keys: make([]string, d.window),
+			set:  make(map[string]struct{}, d.window),
+		}
+		d.vehicles[vehicleID] = recent
+	}
+
+	if _, ok := recent.set[key]; ok {
+		return true
+	}
+
+	if old := recent.keys[recent.next]; old != "" {
+		delete(recent.set, old)
+	}
+	recent.keys[recent.next] = key
+	recent.next = (recent.next + 1) % len(recent.keys)
+	recent.set[key] = struct{}{}
+	return false
+}
+
+// Forget drops key so a message whose processing failed can be retried. Its
+// ring slot is cleared too, otherwise a retry that records the key again would
+// have it evicted from the set when the stale slot comes round.
+func (d *deduplicator) Forget(vehicleID, key string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	recent := d.vehicles[vehicleID]
+	if recent == nil {
+		return
+	}
+	if _, ok := recent.set[key]; !ok {
+		return
+	}
+
+	delete(recent.set, key)
+	for i, k := range recent.keys {
+		if k == key {
+			recent.keys[i] = ""
+			break
+		}
+	}
+}
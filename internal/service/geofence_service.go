@@ -0,0 +1,472 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/fahri/go-tije/internal/config"
+	"github.com/fahri/go-tije/internal/domain"
+	"github.com/fahri/go-tije/internal/repository"
+	"github.com/fahri/go-tije/pkg/geofence"
+)
+
+type GeofenceService interface {
+	List(ctx context.Context) ([]*domain.Geofence, error)
+	Get(ctx context.Context, id string) (*domain.Geofence, error)
+	Create(ctx context.Context, geofence *domain.Geofence) error
+	Update(ctx context.Context, geofence *domain.Geofence) error
+	Delete(ctx context.Context, id string) error
+	Import(ctx context.Context, geofences []*domain.Geofence) error
+	Refresh(ctx context.Context) error
+	Watch(ctx context.Context, interval time.Duration)
+	Evaluate(ctx context.Context, location *domain.VehicleLocation) ([]*domain.GeofenceEvent, error)
+}
+
+type geofenceService struct {
+	repo      repository.GeofenceRepository
+	stateRepo repository.GeofenceStateRepository
+	groupRepo repository.GroupRepository
+	config    *config.GeofenceConfig
+
+	mu        sync.RWMutex
+	geofences map[string]*domain.Geofence
+	index     *geofence.Index
+	groups    map[string]*domain.VehicleGroup
+	// memberships maps a vehicle ID to the IDs of its groups.
+	memberships map[string][]string
+}
+
+func NewGeofenceService(repo repository.GeofenceRepository, stateRepo repository.GeofenceStateRepository, groupRepo repository.GroupRepository, cfg *config.GeofenceConfig) GeofenceService {
+	return &geofenceService{
+		repo:      repo,
+		stateRepo: stateRepo,
+		groupRepo: groupRepo,
+		config:    cfg,
+	}
+}
+
+func (s *geofenceService) List(ctx context.Context) ([]*domain.Geofence, error) {
+	return s.repo.FindAll(ctx)
+}
+
+func (s *geofenceService) Get(ctx context.Context, id string) (*domain.Geofence, error) {
+	return s.repo.FindByID(ctx, id)
+}
+
+func (s *geofenceService) Create(ctx context.Context, geofence *domain.Geofence) error {
+	if err := geofence.Validate(); err != nil {
+		return err
+	}
+	return s.repo.Create(ctx, geofence)
+}
+
+func (s *geofenceService) Update(ctx context.Context, geofence *domain.Geofence) error {
+	if err := geofence.Validate(); err != nil {
+		return err
+	}
+	return s.repo.Update(ctx, geofence)
+}
+
+func (s *geofenceService) Delete(ctx context.Context, id string) error {
+	return s.repo.Delete(ctx, id)
+}
+
+// Import validates every zone before writing any of them, then writes them
+// all or none. Zones carrying an ID replace the stored zone with that ID.
+func (s *geofenceService) Import(ctx context.Context, geofences []*domain.Geofence) error {
+	for i, geofence := range geofences {
+		if err := geofence.Validate(); err != nil {
+			return fmt.Errorf("geofence %d: %v", i, err)
+		}
+	}
+
+	return s.repo.Import(ctx, geofences)
+}
+
+func (s *geofenceService) Refresh(ctx context.Context) error {
+	geofences, err := s.repo.FindActive(ctx)
+	if err != nil {
+		return err
+	}
+
+	byID := make(map[string]*domain.Geofence, len(geofences))
+	items := make([]geofence.IndexItem, 0, len(geofences))
+	for _, zone := range geofences {
+		byID[zone.ID] = zone
+		items = append(items, geofence.IndexItem{ID: zone.ID, Box: boundingBox(zone)})
+	}
+	index := geofence.NewIndex(items)
+
+	groups, err := s.groupRepo.FindAll(ctx)
+	if err != nil {
+		return err
+	}
+
+	groupsByID := make(map[string]*domain.VehicleGroup, len(groups))
+	memberships := make(map[string][]string)
+	for _, group := range groups {
+		groupsByID[group.ID] = group
+		for _, vehicleID := range group.VehicleIDs {
+			memberships[vehicleID] = append(memberships[vehicleID], group.ID)
+		}
+	}
+
+	s.mu.Lock()
+	s.geofences = byID
+	s.index = index
+	s.groups = groupsByID
+	s.memberships = memberships
+	s.mu.Unlock()
+
+	return nil
+}
+
+// Watch reloads the zones whenever the API reports a change and, as a
+// fallback for missed notifications, every interval.
+func (s *geofenceService) Watch(ctx context.Context, interval time.Duration) {
+	go s.listen(ctx)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := s.Refresh(ctx); err != nil {
+				log.Printf("Failed to refresh geofences: %v", err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (s *geofenceService) listen(ctx context.Context) {
+	for {
+		err := s.repo.Listen(ctx, func(id string) {
+			if err := s.Refresh(ctx); err != nil {
+				log.Printf("Failed to refresh geofences after change to %s: %v", id, err)
+			}
+		})
+		if ctx.Err() != nil {
+			return
+		}
+		log.Printf("Geofence change listener stopped: %v", err)
+
+		select {
+		case <-time.After(5 * time.Second):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Evaluate compares the location with the stored per-zone state of the vehicle
+// and returns an event for every confirmed enter, exit or dwell transition. It
+// returns ErrLateLocation, and leaves the state alone, for a location older
+// than the last one evaluated for the vehicle.
+func (s *geofenceService) Evaluate(ctx context.Context, location *domain.VehicleLocation) ([]*domain.GeofenceEvent, error) {
+	// Replicas sharing a subscription can receive consecutive points of one
+	// vehicle; the lock stops them from reading the same state and both
+	// firing the transition. The last evaluated timestamp is read under the
+	// same lock, so a point another replica has already overtaken is turned
+	// away as late instead of rolling the state back.
+	var events []*domain.GeofenceEvent
+	err := s.stateRepo.WithVehicleLock(ctx, location.VehicleID, func(stateRepo repository.GeofenceStateRepository) error {
+		last, err := stateRepo.LastEvaluated(ctx, location.VehicleID)
+		if err != nil {
+			return err
+		}
+		if location.Timestamp < last {
+			return domain.ErrLateLocation
+		}
+
+		events, err = s.evaluate(ctx, stateRepo, location)
+		if err != nil {
+			return err
+		}
+		return stateRepo.SetLastEvaluated(ctx, location.VehicleID, location.Timestamp)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
+
+func (s *geofenceService) evaluate(ctx context.Context, stateRepo repository.GeofenceStateRepository, location *domain.VehicleLocation) ([]*domain.GeofenceEvent, error) {
+	states, err := stateRepo.FindByVehicle(ctx, location.VehicleID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	geofences, index, groups := s.geofences, s.index, s.groups
+	groupIDs := s.memberships[location.VehicleID]
+	s.mu.RUnlock()
+
+	target := geofence.Point{
+		Latitude:  location.Latitude,
+		Longitude: location.Longitude,
+	}
+
+	// Zones with existing state are always re-evaluated, so a vehicle inside
+	// the hysteresis buffer or with a pending transition is not forgotten.
+	previous := make(map[string]*domain.GeofenceState, len(states))
+	candidates := make(map[string]*domain.Geofence)
+	for _, state := range states {
+		previous[state.GeofenceID] = state
+		if zone, ok := geofences[state.GeofenceID]; ok && zone.AppliesTo(location.VehicleID, groupIDs) {
+			// Outside its schedule a zone is not evaluated and keeps its state.
+			if zone.Schedule.Active(location.Timestamp) {
+				candidates[zone.ID] = zone
+			}
+			continue
+		}
+
+		// Zones that were deactivated or unassigned in the meantime are
+		// closed silently.
+		if state.Inside || state.PendingCount > 0 {
+			state.Inside, state.DwellNotified = false, false
+			state.PendingCount, state.PendingSince = 0, 0
+			if err := stateRepo.Save(ctx, state); err != nil {
+				return nil, err
+			}
+		}
+	}
+	for _, zone := range s.match(geofences, index, location, groupIDs) {
+		candidates[zone.ID] = zone
+	}
+
+	var events []*domain.GeofenceEvent
+	for id, zone := range candidates {
+		state, ok := previous[id]
+		if !ok {
+			state = &domain.GeofenceState{
+				VehicleID:  location.VehicleID,
+				GeofenceID: id,
+			}
+		}
+
+		inside := observeInside(zone, state.Inside, target)
+
+		var event *domain.GeofenceEvent
+		var changed bool
+		if zone.Type == domain.GeofenceTypeCorridor {
+			event, changed = s.advanceCorridor(zone, state, inside, location)
+		} else {
+			event, changed = s.advance(zone, state, inside, location)
+		}
+		if changed {
+			if err := stateRepo.Save(ctx, state); err != nil {
+				return events, err
+			}
+		}
+		if event != nil {
+			if group := groups[eventGroup(zone, groupIDs)]; group != nil {
+				event.GroupID, event.GroupName = group.ID, group.Name
+			}
+			events = append(events, event)
+		}
+	}
+
+	return events, nil
+}
+
+// advance applies one observation to the state and reports the confirmed
+// event, if any, and whether the state needs to be saved.
+func (s *geofenceService) advance(zone *domain.Geofence, state *domain.GeofenceState, inside bool, location *domain.VehicleLocation) (*domain.GeofenceEvent, bool) {
+	if inside == state.Inside {
+		changed := state.PendingCount > 0
+		state.PendingCount, state.PendingSince = 0, 0
+
+		if inside && !state.DwellNotified {
+			if duration := location.Timestamp - state.EnteredAt; duration >= s.dwellSeconds(zone) {
+				state.DwellNotified = true
+				return newGeofenceEvent(zone, location, domain.GeofenceEventDwell, duration), true
+			}
+		}
+		return nil, changed
+	}
+
+	if state.PendingCount == 0 {
+		state.PendingSince = location.Timestamp
+	}
+	state.PendingCount++
+
+	if state.PendingCount < max(zone.MinPoints, 1) || location.Timestamp-state.PendingSince < int64(zone.MinDurationSeconds) {
+		return nil, true
+	}
+
+	since := state.PendingSince
+	state.PendingCount, state.PendingSince = 0, 0
+	state.DwellNotified = false
+
+	if inside {
+		state.Inside = true
+		state.EnteredAt = since
+		return newGeofenceEvent(zone, location, domain.GeofenceEventEnter, 0), true
+	}
+
+	state.Inside = false
+	return newGeofenceEvent(zone, location, domain.GeofenceEventExit, since-state.EnteredAt), true
+}
+
+// advanceCorridor tracks whether an assigned vehicle is on its route. Once it
+// has been on the corridor, leaving it for longer than the deviation time
+// publishes a single route_deviation until the vehicle is back on route.
+// Inside means "has joined the route", PendingSince is when it left it and
+// DwellNotified marks the deviation as already reported.
+func (s *geofenceService) advanceCorridor(zone *domain.Geofence, state *domain.GeofenceState, onRoute bool, location *domain.VehicleLocation) (*domain.GeofenceEvent, bool) {
+	if onRoute {
+		changed := !state.Inside || state.PendingCount > 0 || state.DwellNotified
+		if !state.Inside {
+			state.EnteredAt = location.Timestamp
+		}
+		state.Inside = true
+		state.PendingCount, state.PendingSince = 0, 0
+		state.DwellNotified = false
+		return nil, changed
+	}
+
+	if !state.Inside {
+		return nil, false
+	}
+
+	if state.PendingCount == 0 {
+		state.PendingSince = location.Timestamp
+	}
+	state.PendingCount++
+
+	duration := location.Timestamp - state.PendingSince
+	if state.DwellNotified || state.PendingCount < max(zone.MinPoints, 1) || duration < s.deviationSeconds(zone) {
+		return nil, true
+	}
+
+	state.DwellNotified = true
+	return newGeofenceEvent(zone, location, domain.GeofenceEventRouteDeviation, duration), true
+}
+
+func (s *geofenceService) match(geofences map[string]*domain.Geofence, index *geofence.Index, location *domain.VehicleLocation, groupIDs []string) []*domain.Geofence {
+	if index == nil {
+		return nil
+	}
+
+	target := geofence.Point{
+		Latitude:  location.Latitude,
+		Longitude: location.Longitude,
+	}
+
+	var matched []*domain.Geofence
+	for _, id := range index.Search(target) {
+		zone := geofences[id]
+		if zone.AppliesTo(location.VehicleID, groupIDs) && zone.Schedule.Active(location.Timestamp) && contains(zone, target) {
+			matched = append(matched, zone)
+		}
+	}
+
+	return matched
+}
+
+func (s *geofenceService) dwellSeconds(zone *domain.Geofence) int64 {
+	if zone.DwellSeconds > 0 {
+		return int64(zone.DwellSeconds)
+	}
+	return int64(s.config.DwellTime / time.Second)
+}
+
+func (s *geofenceService) deviationSeconds(zone *domain.Geofence) int64 {
+	if zone.DeviationSeconds > 0 {
+		return int64(zone.DeviationSeconds)
+	}
+	return int64(s.config.DeviationTime / time.Second)
+}
+
+func boundingBox(zone *domain.Geofence) geofence.BBox {
+	switch zone.Type {
+	case domain.GeofenceTypePolygon:
+		return zone.Polygon.BBox()
+	case domain.GeofenceTypeCorridor:
+		return geofence.CorridorBBox(zone.Line, zone.Radius)
+	default:
+		center := geofence.Point{
+			Latitude:  zone.Latitude,
+			Longitude: zone.Longitude,
+		}
+		return geofence.CircleBBox(center, zone.Radius)
+	}
+}
+
+// observeInside reports which side of the zone the point is on. A vehicle
+// that is confirmed inside stays inside while it is within the zone's
+// hysteresis buffer beyond the boundary.
+func observeInside(zone *domain.Geofence, wasInside bool, target geofence.Point) bool {
+	if contains(zone, target) {
+		return true
+	}
+	if !wasInside || zone.HysteresisMeters <= 0 {
+		return false
+	}
+	return distanceOutside(zone, target) <= zone.HysteresisMeters
+}
+
+func distanceOutside(zone *domain.Geofence, target geofence.Point) float64 {
+	switch zone.Type {
+	case domain.GeofenceTypePolygon:
+		return zone.Polygon.DistanceToBoundary(target)
+	case domain.GeofenceTypeCorridor:
+		return geofence.DistanceToPolyline(zone.Line, target) - zone.Radius
+	default:
+		center := geofence.Point{
+			Latitude:  zone.Latitude,
+			Longitude: zone.Longitude,
+		}
+		return geofence.CalculateDistance(center, target) - zone.Radius
+	}
+}
+
+func contains(zone *domain.Geofence, target geofence.Point) bool {
+	switch zone.Type {
+	case domain.GeofenceTypePolygon:
+		return geofence.IsWithinPolygon(zone.Polygon, target)
+	case domain.GeofenceTypeCorridor:
+		return geofence.IsWithinCorridor(zone.Line, target, zone.Radius)
+	default:
+		center := geofence.Point{
+			Latitude:  zone.Latitude,
+			Longitude: zone.Longitude,
+		}
+		return geofence.IsWithinRadius(center, target, zone.Radius)
+	}
+}
+
+// eventGroup picks the group named in an event: the vehicle group the zone is
+// assigned through or, for zones that apply to everyone or to the vehicle
+// directly, the first group of the vehicle.
+func eventGroup(zone *domain.Geofence, groupIDs []string) string {
+	if id := zone.AssignedGroup(groupIDs); id != "" {
+		return id
+	}
+	if len(groupIDs) > 0 {
+		return groupIDs[0]
+	}
+	return ""
+}
+
+func newGeofenceEvent(zone *domain.Geofence, location *domain.VehicleLocation, event string, duration int64) *domain.GeofenceEvent {
+	return &domain.GeofenceEvent{
+		VehicleID:    location.VehicleID,
+		GeofenceID:   zone.ID,
+		GeofenceName: zone.Name,
+		Event:        event,
+		Location: domain.Location{
+			Latitude:  location.Latitude,
+			Longitude: location.Longitude,
+		},
+		Timestamp: location.Timestamp,
+		Duration:  duration,
+	}
+}
@@ -0,0 +1,62 @@
+package service
+
+import (
+	"context"
+
+	"github.com/fahri/go-tije/internal/domain"
+	"github.com/fahri/go-tije/internal/repository"
+)
+
+type GroupService interface {
+	List(ctx context.Context) ([]*domain.VehicleGroup, error)
+	Get(ctx context.Context, id string) (*domain.VehicleGroup, error)
+	Create(ctx context.Context, group *domain.VehicleGroup) error
+	Update(ctx context.Context, group *domain.VehicleGroup) error
+	Delete(ctx context.Context, id string) error
+	AddVehicle(ctx context.Context, groupID, vehicleID string) error
+	RemoveVehicle(ctx context.Context, groupID, vehicleID string) error
+}
+
+type groupService struct {
+	repo repository.GroupRepository
+}
+
+func NewGroupService(repo repository.GroupRepository) GroupService {
+	return &groupService{
+		repo: repo,
+	}
+}
+
+func (s *groupService) List(ctx context.Context) ([]*domain.VehicleGroup, error) {
+	return s.repo.FindAll(ctx)
+}
+
+func (s *groupService) Get(ctx context.Context, id string) (*domain.VehicleGroup, error) {
+	return s.repo.FindByID(ctx, id)
+}
+
+func (s *groupService) Create(ctx context.Context, group *domain.VehicleGroup) error {
+	if err := group.Validate(); err != nil {
+		return err
+	}
+	return s.repo.Create(ctx, group)
+}
+
+func (s *groupService) Update(ctx context.Context, group *domain.VehicleGroup) error {
+	if err := group.Validate(); err != nil {
+		return err
+	}
+	return s.repo.Update(ctx, group)
+}
+
+func (s *groupService) Delete(ctx context.Context, id string) error {
+	return s.repo.Delete(ctx, id)
+}
+
+func (s *groupService) AddVehicle(ctx context.Context, groupID, vehicleID string) error {
+	return s.repo.AddVehicle(ctx, groupID, vehicleID)
+}
+
+func (s *groupService) RemoveVehicle(ctx context.Context, groupID, vehicleID string) error {
+	return s.repo.RemoveVehicle(ctx, groupID, vehicleID)
+}
@@ -0,0 +1,106 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/fahri/go-tije/internal/domain"
+	"github.com/fahri/go-tije/internal/repository"
+)
+
+type PresenceService interface {
+	ProcessStatus(ctx context.Context, vehicleID string, payload []byte, retained bool) error
+	GetPresence(ctx context.Context, vehicleID string) (*domain.VehiclePresence, error)
+}
+
+type presenceService struct {
+	repo     repository.PresenceRepository
+	vehicles repository.VehicleRepository
+}
+
+func NewPresenceService(repo repository.PresenceRepository, vehicles repository.VehicleRepository) PresenceService {
+	return &presenceService{
+		repo:     repo,
+		vehicles: vehicles,
+	}
+}
+
+// ProcessStatus records a message from /fleet/vehicle/{id}/status, either
+// "online"/"offline" as plain text or {"status": ..., "timestamp": ...}.
+// "offline" is usually the Last Will published by the broker, and a retained
+// message is replayed by the broker, so neither counts as hearing from the
+// tracker.
+func (s *presenceService) ProcessStatus(ctx context.Context, vehicleID string, payload []byte, retained bool) error {
+	status, timestamp, err := parseStatus(payload)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().Unix()
+	if timestamp <= 0 || timestamp > now {
+		timestamp = now
+	}
+
+	seen := status == domain.PresenceOnline && !retained
+	return s.repo.Save(ctx, vehicleID, status, timestamp, seen)
+}
+
+// GetPresence combines the reported status with the newest stored location. A
+// vehicle that never reported a status is "unknown" but may still have been
+// seen through its locations.
+func (s *presenceService) GetPresence(ctx context.Context, vehicleID string) (*domain.VehiclePresence, error) {
+	presence, err := s.repo.Find(ctx, vehicleID)
+	if err != nil && err.Error() != "presence not found" {
+		return nil, err
+	}
+
+	location, err := s.vehicles.FindLatest(ctx, vehicleID)
+	if err != nil && err.Error() != "vehicle not found" {
+		return nil, err
+	}
+
+	if presence == nil && location == nil {
+		return nil, fmt.Errorf("vehicle not found")
+	}
+	if presence == nil {
+		presence = &domain.VehiclePresence{
+			VehicleID: vehicleID,
+			Status:    domain.PresenceUnknown,
+		}
+	}
+	if location != nil && (presence.LastSeen == nil || location.CreatedAt.After(*presence.LastSeen)) {
+		presence.LastSeen = &location.CreatedAt
+	}
+
+	return presence, nil
+}
+
+func parseStatus(payload []byte) (string, int64, error) {
+	payload = bytes.TrimSpace(payload)
+
+	var status string
+	var timestamp int64
+	if len(payload) > 0 && payload[0] == '{' {
+		var message struct {
+			Status    string `json:"status"`
+			Timestamp int64  `json:"timestamp"`
+		}
+		if err := json.Unmarshal(payload, &message); err != nil {
+			return "", 0, fmt.Errorf("invalid status message: %v", err)
+		}
+		status, timestamp = message.Status, message.Timestamp
+	} else {
+		status = string(payload)
+	}
+
+	status = strings.ToLower(strings.TrimSpace(status))
+	if status != domain.PresenceOnline && status != domain.PresenceOffline {
+		return "", 0, fmt.Errorf("status must be %s or %s, got %q", domain.PresenceOnline, domain.PresenceOffline, status)
+	}
+
+	return status, timestamp, nil
+}
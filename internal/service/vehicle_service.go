@@ -3,39 +3,121 @@ package service
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"log"
+	"sync"
+	"time"
 
+	"github.com/fahri/go-tije/internal/codec"
 	"github.com/fahri/go-tije/internal/config"
 	"github.com/fahri/go-tije/internal/domain"
+	"github.com/fahri/go-tije/internal/nmea"
 	"github.com/fahri/go-tije/internal/repository"
-	"github.com/fahri/go-tije/pkg/geofence"
 	"github.com/fahri/go-tije/pkg/rabbitmq"
 )
 
 type VehicleService interface {
 	ProcessLocation(ctx context.Context, message []byte) error
+	ProcessPayload(ctx context.Context, format codec.Format, payload []byte) error
+	ProcessMessage(ctx context.Context, message *domain.LocationMessage) error
+	ProcessNMEA(ctx context.Context, vehicleID string, payload []byte) error
 	GetLatestLocation(ctx context.Context, vehicleID string) (*domain.VehicleLocation, error)
 	GetLocationHistory(ctx context.Context, vehicleID string, start, end int64) ([]*domain.VehicleLocation, error)
+	GetRejectionCounts(ctx context.Context, start, end int64) (map[domain.RejectReason]int64, error)
+	GetRecentRejections(ctx context.Context, reason domain.RejectReason, limit int) ([]*domain.InvalidLocation, error)
 }
 
 type vehicleService struct {
 	repo      repository.VehicleRepository
+	invalid   repository.InvalidLocationRepository
 	rabbitmq  *rabbitmq.Publisher
-	geofenceConfig *config.GeofenceConfig
+	geofences GeofenceService
+	config    *config.IngestConfig
+	dedup     *deduplicator
+	
+	mu     sync.Mutex
+	latest map[string]int64
 }
 
-func NewVehicleService(repo repository.VehicleRepository, rmq *rabbitmq.Publisher, geofenceCfg *config.GeofenceConfig) VehicleService {
+func NewVehicleService(repo repository.VehicleRepository, invalid repository.InvalidLocationRepository, rmq *rabbitmq.Publisher, geofences GeofenceService, cfg *config.IngestConfig) VehicleService {
 	return &vehicleService{
 		repo:      repo,
+		invalid:   invalid,
 		rabbitmq:  rmq,
-		geofenceConfig: geofenceCfg,
+		geofences: geofences,
+		config:    cfg,
+		dedup:     newDeduplicator(cfg.DedupWindow),
+		latest:    make(map[string]int64),
 	}
 }
 
+// ProcessLocation handles a JSON location message, see ProcessPayload.
 func (s *vehicleService) ProcessLocation(ctx context.Context, message []byte) error {
-	var locationMsg domain.LocationMessage
-	if err := json.Unmarshal(message, &locationMsg); err != nil {
-		return err
+	return s.ProcessPayload(ctx, codec.FormatJSON, message)
+}
+
+// ProcessPayload decodes a location in the given format and hands it to
+// ProcessMessage. An undecodable payload is rejected as invalid_payload.
+func (s *vehicleService) ProcessPayload(ctx context.Context, format codec.Format, payload []byte) error {
+	locationMsg, err := codec.Decode(format, payload)
+	if err != nil {
+		return s.reject(ctx, payload, "", &domain.ValidationError{
+			Reason:  domain.RejectInvalidPayload,
+			Message: err.Error(),
+		})
+	}
+	
+	return s.process(ctx, locationMsg, payload)
+}
+
+// ProcessNMEA handles NMEA 0183 sentences from a vehicle, one per line. Each
+// fix they describe goes through ProcessMessage; the first error is returned.
+// Sentences without a fix are ignored. Malformed sentences, such as a bad
+// checksum, are skipped, and a payload with nothing but malformed sentences is
+// rejected as invalid_payload.
+func (s *vehicleService) ProcessNMEA(ctx context.Context, vehicleID string, payload []byte) error {
+	messages, err := nmea.Parse(vehicleID, payload)
+	if err != nil && len(messages) > 0 {
+		log.Printf("Skipped malformed NMEA sentences from %s: %v", vehicleID, err)
+	} else if err != nil {
+		return s.reject(ctx, payload, vehicleID, &domain.ValidationError{
+			Reason:  domain.RejectInvalidPayload,
+			Message: err.Error(),
+		})
+	}
+	
+	var first error
+	for _, message := range messages {
+		if err := s.process(ctx, message, payload); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// ProcessMessage stores a decoded location and evaluates it against the
+// geofences. A message that fails validation is recorded in the
+// invalid-locations sink and a *domain.ValidationError is returned. A location
+// that was already ingested is dropped with domain.ErrDuplicateLocation. A
+// location older than the vehicle's newest one is stored as backfill and not
+// evaluated, so it cannot roll back the geofence state.
+func (s *vehicleService) ProcessMessage(ctx context.Context, message *domain.LocationMessage) error {
+	return s.process(ctx, message, nil)
+}
+
+// process handles a decoded location; payload is the raw message kept with a
+// rejection, or nil to keep the location re-encoded as JSON.
+func (s *vehicleService) process(ctx context.Context, locationMsg *domain.LocationMessage, payload []byte) error {
+	if err := locationMsg.Validate(time.Now(), s.config.MaxFutureSkew, s.config.MaxAge); err != nil {
+		if payload == nil {
+			payload, _ = json.Marshal(locationMsg)
+		}
+		return s.reject(ctx, payload, locationMsg.VehicleID, err)
+	}
+	
+	key := dedupKey(locationMsg)
+	if s.dedup.Seen(locationMsg.VehicleID, key) {
+		return domain.ErrDuplicateLocation
 	}
 	
 	location := &domain.VehicleLocation{
@@ -43,23 +125,44 @@ func (s *vehicleService) ProcessLocation(ctx context.Context, message []byte) er
 		Latitude:  locationMsg.Latitude,
 		Longitude: locationMsg.Longitude,
 		Timestamp: locationMsg.Timestamp,
+		MessageID: locationMsg.MessageID,
+		Telemetry: locationMsg.Telemetry,
 	}
 	
+	late, err := s.isLate(ctx, location)
+	if err != nil {
+		s.dedup.Forget(location.VehicleID, key)
+		return err
+	}
+	location.Backfill = late
+	
 	if err := s.repo.Save(ctx, location); err != nil {
+		if err != domain.ErrDuplicateLocation {
+			s.dedup.Forget(location.VehicleID, key)
+		}
 		return err
 	}
 	
-	if s.checkGeofence(location) {
-		event := domain.GeofenceEvent{
-			VehicleID: location.VehicleID,
-			Event:     "geofence_entry",
-			Location: domain.Location{
-				Latitude:  location.Latitude,
-				Longitude: location.Longitude,
-			},
-			Timestamp: location.Timestamp,
+	if location.Backfill {
+		log.Printf("Stored late location for %s as backfill (timestamp %d)", location.VehicleID, location.Timestamp)
+		return nil
+	}
+	
+	events, err := s.geofences.Evaluate(ctx, location)
+	if err == domain.ErrLateLocation {
+		// Another replica has already evaluated a newer point.
+		location.Backfill = true
+		if err := s.repo.MarkBackfill(ctx, location.ID); err != nil {
+			log.Printf("Failed to mark late location of %s as backfill: %v", location.VehicleID, err)
 		}
-		
+		log.Printf("Stored late location for %s as backfill (timestamp %d)", location.VehicleID, location.Timestamp)
+		return nil
+	}
+	if err != nil {
+		log.Printf("Failed to evaluate geofences for %s: %v", location.VehicleID, err)
+	}
+	
+	for _, event := range events {
 		eventData, _ := json.Marshal(event)
 		if err := s.rabbitmq.Publish(ctx, eventData); err != nil {
 			log.Printf("Failed to publish geofence event: %v", err)
@@ -69,6 +172,54 @@ func (s *vehicleService) ProcessLocation(ctx context.Context, message []byte) er
 	return nil
 }
 
+// isLate reports whether location is older than the newest location seen for
+// its vehicle, and otherwise records it as the newest. The first location of a
+// vehicle since startup is compared with the latest stored one.
+func (s *vehicleService) isLate(ctx context.Context, location *domain.VehicleLocation) (bool, error) {
+	s.mu.Lock()
+	latest, ok := s.latest[location.VehicleID]
+	s.mu.Unlock()
+	
+	if !ok {
+		stored, err := s.repo.FindLatest(ctx, location.VehicleID)
+		if err != nil && err.Error() != "vehicle not found" {
+			return false, err
+		}
+		if stored != nil {
+			latest = stored.Timestamp
+		}
+	}
+	
+	late := location.Timestamp < latest
+	
+	s.mu.Lock()
+	s.latest[location.VehicleID] = max(s.latest[location.VehicleID], latest, location.Timestamp)
+	s.mu.Unlock()
+	return late, nil
+}
+
+func (s *vehicleService) reject(ctx context.Context, message []byte, vehicleID string, err error) error {
+	var validationErr *domain.ValidationError
+	if !errors.As(err, &validationErr) {
+		return err
+	}
+	
+	if len(vehicleID) > 255 {
+		vehicleID = vehicleID[:255]
+	}
+	invalid := &domain.InvalidLocation{
+		VehicleID: vehicleID,
+		Reason:    validationErr.Reason,
+		Message:   validationErr.Message,
+		Payload:   message,
+	}
+	if saveErr := s.invalid.Save(ctx, invalid); saveErr != nil {
+		log.Printf("Failed to record rejected location (%s): %v", validationErr.Reason, saveErr)
+	}
+	
+	return err
+}
+
 func (s *vehicleService) GetLatestLocation(ctx context.Context, vehicleID string) (*domain.VehicleLocation, error) {
 	return s.repo.FindLatest(ctx, vehicleID)
 }
@@ -77,16 +228,10 @@ func (s *vehicleService) GetLocationHistory(ctx context.Context, vehicleID strin
 	return s.repo.FindHistory(ctx, vehicleID, start, end)
 }
 
-func (s *vehicleService) checkGeofence(location *domain.VehicleLocation) bool {
-	center := geofence.Point{
-		Latitude:  s.geofenceConfig.Latitude,
-		Longitude: s.geofenceConfig.Longitude,
-	}
-	
-	target := geofence.Point{
-		Latitude:  location.Latitude,
-		Longitude: location.Longitude,
-	}
-	
-	return geofence.IsWithinRadius(center, target, s.geofenceConfig.Radius)
+func (s *vehicleService) GetRejectionCounts(ctx context.Context, start, end int64) (map[domain.RejectReason]int64, error) {
+	return s.invalid.CountByReason(ctx, start, end)
+}
+
+func (s *vehicleService) GetRecentRejections(ctx context.Context, reason domain.RejectReason, limit int) ([]*domain.InvalidLocation, error) {
+	return s.invalid.FindRecent(ctx, reason, limit)
 }
\ No newline at end of file
@@ -4,9 +4,194 @@ CREATE TABLE IF NOT EXISTS vehicle_locations (
     latitude DECIMAL(10, 6) NOT NULL,
     longitude DECIMAL(10, 6) NOT NULL,
     timestamp BIGINT NOT NULL,
+    message_id VARCHAR(100),
+    backfill BOOLEAN NOT NULL DEFAULT FALSE,
+    speed DOUBLE PRECISION,
+    heading DOUBLE PRECISION,
+    altitude DOUBLE PRECISION,
+    hdop DOUBLE PRECISION,
+    accuracy DOUBLE PRECISION,
+    satellites INTEGER,
+    fix_quality SMALLINT,
+    ignition BOOLEAN,
+    odometer DOUBLE PRECISION,
+    battery_voltage DOUBLE PRECISION,
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 );
 
-CREATE INDEX idx_vehicle_id ON vehicle_locations(vehicle_id);
-CREATE INDEX idx_timestamp ON vehicle_locations(timestamp);
-CREATE INDEX idx_vehicle_timestamp ON vehicle_locations(vehicle_id, timestamp DESC);
\ No newline at end of file
+-- This script is safe to re-run: it also brings a database created by an
+-- earlier version up to date.
+ALTER TABLE vehicle_locations
+    ADD COLUMN IF NOT EXISTS message_id VARCHAR(100),
+    ADD COLUMN IF NOT EXISTS backfill BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN IF NOT EXISTS speed DOUBLE PRECISION,
+    ADD COLUMN IF NOT EXISTS heading DOUBLE PRECISION,
+    ADD COLUMN IF NOT EXISTS altitude DOUBLE PRECISION,
+    ADD COLUMN IF NOT EXISTS hdop DOUBLE PRECISION,
+    ADD COLUMN IF NOT EXISTS accuracy DOUBLE PRECISION,
+    ADD COLUMN IF NOT EXISTS satellites INTEGER,
+    ADD COLUMN IF NOT EXISTS fix_quality SMALLINT,
+    ADD COLUMN IF NOT EXISTS ignition BOOLEAN,
+    ADD COLUMN IF NOT EXISTS odometer DOUBLE PRECISION,
+    ADD COLUMN IF NOT EXISTS battery_voltage DOUBLE PRECISION;
+
+CREATE INDEX IF NOT EXISTS idx_vehicle_id ON vehicle_locations(vehicle_id);
+CREATE INDEX IF NOT EXISTS idx_timestamp ON vehicle_locations(timestamp);
+CREATE INDEX IF NOT EXISTS idx_vehicle_timestamp ON vehicle_locations(vehicle_id, timestamp DESC);
+
+-- Locations stored before duplicates were rejected may repeat; keep the first
+-- stored copy so the unique indexes can be built.
+DO $$
+BEGIN
+    IF to_regclass('ux_vehicle_message') IS NULL THEN
+        DELETE FROM vehicle_locations a
+        USING vehicle_locations b
+        WHERE a.vehicle_id = b.vehicle_id
+            AND a.message_id = b.message_id
+            AND (a.created_at, a.id) > (b.created_at, b.id);
+    END IF;
+    IF to_regclass('ux_vehicle_point') IS NULL THEN
+        DELETE FROM vehicle_locations a
+        USING vehicle_locations b
+        WHERE a.vehicle_id = b.vehicle_id
+            AND a.timestamp = b.timestamp
+            AND a.message_id IS NULL
+            AND b.message_id IS NULL
+            AND (a.created_at, a.id) > (b.created_at, b.id);
+    END IF;
+END $$;
+
+CREATE UNIQUE INDEX IF NOT EXISTS ux_vehicle_message ON vehicle_locations(vehicle_id, message_id) WHERE message_id IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS ux_vehicle_point ON vehicle_locations(vehicle_id, timestamp) WHERE message_id IS NULL;
+
+CREATE TABLE IF NOT EXISTS invalid_locations (
+    id VARCHAR(36) PRIMARY KEY,
+    vehicle_id VARCHAR(255) NOT NULL DEFAULT '',
+    reason VARCHAR(50) NOT NULL,
+    message TEXT NOT NULL,
+    payload BYTEA,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_invalid_locations_created ON invalid_locations(created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_invalid_locations_reason ON invalid_locations(reason, created_at DESC);
+
+CREATE TABLE IF NOT EXISTS geofences (
+    id VARCHAR(36) PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    type VARCHAR(20) NOT NULL DEFAULT 'circle',
+    latitude DECIMAL(10, 6) NOT NULL DEFAULT 0,
+    longitude DECIMAL(10, 6) NOT NULL DEFAULT 0,
+    radius DOUBLE PRECISION NOT NULL DEFAULT 0,
+    polygon JSONB,
+    line JSONB,
+    vehicle_ids TEXT[] NOT NULL DEFAULT '{}',
+    dwell_seconds INTEGER NOT NULL DEFAULT 0,
+    hysteresis_meters DOUBLE PRECISION NOT NULL DEFAULT 0,
+    min_points INTEGER NOT NULL DEFAULT 1,
+    min_duration_seconds INTEGER NOT NULL DEFAULT 0,
+    deviation_seconds INTEGER NOT NULL DEFAULT 0,
+    schedule JSONB,
+    tags TEXT[] NOT NULL DEFAULT '{}',
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+ALTER TABLE geofences
+    ADD COLUMN IF NOT EXISTS line JSONB,
+    ADD COLUMN IF NOT EXISTS vehicle_ids TEXT[] NOT NULL DEFAULT '{}',
+    ADD COLUMN IF NOT EXISTS dwell_seconds INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS hysteresis_meters DOUBLE PRECISION NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS min_points INTEGER NOT NULL DEFAULT 1,
+    ADD COLUMN IF NOT EXISTS min_duration_seconds INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS deviation_seconds INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS schedule JSONB,
+    ADD COLUMN IF NOT EXISTS tags TEXT[] NOT NULL DEFAULT '{}';
+
+CREATE INDEX IF NOT EXISTS idx_geofences_active ON geofences(active);
+
+INSERT INTO geofences (id, name, type, latitude, longitude, radius)
+VALUES ('00000000-0000-0000-0000-000000000001', 'Default Zone', 'circle', -6.2088, 106.8456, 50)
+ON CONFLICT (id) DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS geofence_states (
+    vehicle_id VARCHAR(50) NOT NULL,
+    geofence_id VARCHAR(36) NOT NULL REFERENCES geofences(id) ON DELETE CASCADE,
+    inside BOOLEAN NOT NULL DEFAULT FALSE,
+    entered_at BIGINT NOT NULL DEFAULT 0,
+    dwell_notified BOOLEAN NOT NULL DEFAULT FALSE,
+    pending_count INTEGER NOT NULL DEFAULT 0,
+    pending_since BIGINT NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (vehicle_id, geofence_id)
+);
+
+ALTER TABLE geofence_states
+    ADD COLUMN IF NOT EXISTS pending_count INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS pending_since BIGINT NOT NULL DEFAULT 0;
+
+-- The timestamp of the newest location evaluated per vehicle, shared by all
+-- subscriber replicas so an older point is never evaluated as live.
+CREATE TABLE IF NOT EXISTS geofence_evaluations (
+    vehicle_id VARCHAR(50) PRIMARY KEY,
+    last_timestamp BIGINT NOT NULL,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS vehicle_groups (
+    id VARCHAR(36) PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    type VARCHAR(20) NOT NULL DEFAULT 'route',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS vehicle_group_members (
+    group_id VARCHAR(36) NOT NULL REFERENCES vehicle_groups(id) ON DELETE CASCADE,
+    vehicle_id VARCHAR(50) NOT NULL,
+    PRIMARY KEY (group_id, vehicle_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_vehicle_group_members_vehicle ON vehicle_group_members(vehicle_id);
+
+CREATE TABLE IF NOT EXISTS geofence_groups (
+    geofence_id VARCHAR(36) NOT NULL REFERENCES geofences(id) ON DELETE CASCADE,
+    group_id VARCHAR(36) NOT NULL REFERENCES vehicle_groups(id) ON DELETE RESTRICT,
+    PRIMARY KEY (geofence_id, group_id)
+);
+
+CREATE TABLE IF NOT EXISTS ingest_credentials (
+    id VARCHAR(36) PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    type VARCHAR(20) NOT NULL,
+    vehicle_id VARCHAR(50),
+    group_id VARCHAR(36) REFERENCES vehicle_groups(id) ON DELETE CASCADE,
+    token_hash CHAR(64) NOT NULL UNIQUE,
+    last_used_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS vehicle_presence (
+    vehicle_id VARCHAR(50) PRIMARY KEY,
+    status VARCHAR(10) NOT NULL,
+    changed_at TIMESTAMP NOT NULL,
+    reported_at TIMESTAMP NOT NULL,
+    last_seen_at TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS vehicle_commands (
+    id VARCHAR(36) PRIMARY KEY,
+    vehicle_id VARCHAR(50) NOT NULL,
+    type VARCHAR(30) NOT NULL,
+    params JSONB NOT NULL DEFAULT '{}',
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    error TEXT,
+    expires_at TIMESTAMP NOT NULL,
+    acked_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_vehicle_commands_vehicle ON vehicle_commands(vehicle_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_vehicle_commands_pending ON vehicle_commands(expires_at) WHERE status = 'pending';
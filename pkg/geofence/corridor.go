@@ -0,0 +1,59 @@
+package geofence
+
+import (
+	"encoding/json"
+	"math"
+)
+
+// Polyline is an ordered sequence of points, such as a bus route.
+type Polyline []Point
+
+// DistanceToPolyline returns the distance in meters from target to the nearest
+// segment of the line.
+func DistanceToPolyline(line Polyline, target Point) float64 {
+	switch len(line) {
+	case 0:
+		return math.Inf(1)
+	case 1:
+		return CalculateDistance(line[0], target)
+	}
+
+	distance := math.Inf(1)
+	for i := 1; i < len(line); i++ {
+		distance = math.Min(distance, DistanceToSegment(target, line[i-1], line[i]))
+	}
+	return distance
+}
+
+// IsWithinCorridor reports whether target lies within width meters of the line.
+func IsWithinCorridor(line Polyline, target Point, width float64) bool {
+	return DistanceToPolyline(line, target) <= width
+}
+
+// CorridorBBox returns a box that encloses the line buffered by width meters.
+func CorridorBBox(line Polyline, width float64) BBox {
+	box := Ring(line).BBox()
+	for _, p := range line {
+		box = box.extend(CircleBBox(p, width))
+	}
+	return box
+}
+
+// MarshalJSON writes the line as GeoJSON LineString coordinates. Unlike a
+// Ring it is not closed: the last point does not lead back to the first.
+func (l Polyline) MarshalJSON() ([]byte, error) {
+	coords := make([][2]float64, len(l))
+	for i, p := range l {
+		coords[i] = [2]float64{p.Longitude, p.Latitude}
+	}
+	return json.Marshal(coords)
+}
+
+func (l *Polyline) UnmarshalJSON(data []byte) error {
+	var ring Ring
+	if err := json.Unmarshal(data, &ring); err != nil {
+		return err
+	}
+	*l = Polyline(ring)
+	return nil
+}
@@ -0,0 +1,37 @@
+package geofence
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestPolylineJSONRoundTrip(t *testing.T) {
+	line := Polyline{pt(0, 0), pt(0, 1), pt(1, 1)}
+
+	data, err := json.Marshal(line)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	if want := "[[0,0],[0,1],[1,1]]"; string(data) != want {
+		t.Errorf("got %s, want %s", data, want)
+	}
+
+	var decoded Polyline
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if len(decoded) != len(line) {
+		t.Fatalf("got %d points, want %d", len(decoded), len(line))
+	}
+	for i := range line {
+		if decoded[i] != line[i] {
+			t.Errorf("point %d = %v, want %v", i, decoded[i], line[i])
+		}
+	}
+
+	// The closing segment (1,1)→(0,0) would pass through this point.
+	off := pt(0.5, 0.5)
+	if IsWithinCorridor(decoded, off, 1000) {
+		t.Errorf("%v is on the decoded route, want it off-route", off)
+	}
+}
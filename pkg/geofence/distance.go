@@ -0,0 +1,47 @@
+package geofence
+
+import "math"
+
+// DistanceToSegment returns the distance in meters from p to the segment ab.
+// The segment is projected onto a local equirectangular plane around p,
+// which is accurate for the short edges used by zones and routes.
+func DistanceToSegment(p, a, b Point) float64 {
+	cosLat := math.Cos(toRadians(p.Latitude))
+	project := func(q Point) (float64, float64) {
+		x := toRadians(q.Longitude-p.Longitude) * cosLat * earthRadius
+		y := toRadians(q.Latitude-p.Latitude) * earthRadius
+		return x, y
+	}
+
+	ax, ay := project(a)
+	bx, by := project(b)
+	dx, dy := bx-ax, by-ay
+
+	t := 0.0
+	if lengthSq := dx*dx + dy*dy; lengthSq > 0 {
+		t = math.Max(0, math.Min(1, -(ax*dx+ay*dy)/lengthSq))
+	}
+
+	return math.Hypot(ax+t*dx, ay+t*dy)
+}
+
+// DistanceToBoundary returns the distance in meters from p to the nearest edge of the ring.
+func (r Ring) DistanceToBoundary(p Point) float64 {
+	distance := math.Inf(1)
+	for i := range r {
+		a, b := r[i], r[(i+1)%len(r)]
+		distance = math.Min(distance, DistanceToSegment(p, a, b))
+	}
+	return distance
+}
+
+func (m MultiPolygon) DistanceToBoundary(p Point) float64 {
+	distance := math.Inf(1)
+	for _, polygon := range m {
+		distance = math.Min(distance, polygon.Outer.DistanceToBoundary(p))
+		for _, hole := range polygon.Holes {
+			distance = math.Min(distance, hole.DistanceToBoundary(p))
+		}
+	}
+	return distance
+}
@@ -0,0 +1,171 @@
+package geofence
+
+import (
+	"math"
+	"sort"
+)
+
+// nodeCapacity is the maximum number of children per R-tree node.
+const nodeCapacity = 16
+
+// BBox is an axis-aligned bounding box in degrees.
+type BBox struct {
+	MinLat float64
+	MinLon float64
+	MaxLat float64
+	MaxLon float64
+}
+
+func (b BBox) Contains(p Point) bool {
+	return p.Latitude >= b.MinLat && p.Latitude <= b.MaxLat &&
+		p.Longitude >= b.MinLon && p.Longitude <= b.MaxLon
+}
+
+func (b BBox) extend(o BBox) BBox {
+	return BBox{
+		MinLat: math.Min(b.MinLat, o.MinLat),
+		MinLon: math.Min(b.MinLon, o.MinLon),
+		MaxLat: math.Max(b.MaxLat, o.MaxLat),
+		MaxLon: math.Max(b.MaxLon, o.MaxLon),
+	}
+}
+
+func (b BBox) center() Point {
+	return Point{
+		Latitude:  (b.MinLat + b.MaxLat) / 2,
+		Longitude: (b.MinLon + b.MaxLon) / 2,
+	}
+}
+
+// CircleBBox returns a box that encloses every point within radius meters of center.
+func CircleBBox(center Point, radius float64) BBox {
+	dLat := radius / earthRadius * 180 / math.Pi
+	dLon := 180.0
+	if cos := math.Cos(toRadians(center.Latitude)); cos > 1e-9 {
+		dLon = math.Min(180, dLat/cos)
+	}
+
+	return BBox{
+		MinLat: center.Latitude - dLat,
+		MinLon: center.Longitude - dLon,
+		MaxLat: center.Latitude + dLat,
+		MaxLon: center.Longitude + dLon,
+	}
+}
+
+func (r Ring) BBox() BBox {
+	box := BBox{MinLat: math.Inf(1), MinLon: math.Inf(1), MaxLat: math.Inf(-1), MaxLon: math.Inf(-1)}
+	for _, p := range r {
+		box = box.extend(BBox{MinLat: p.Latitude, MinLon: p.Longitude, MaxLat: p.Latitude, MaxLon: p.Longitude})
+	}
+	return box
+}
+
+// BBox of a multipolygon only needs the outer rings; holes lie inside them.
+func (m MultiPolygon) BBox() BBox {
+	box := BBox{MinLat: math.Inf(1), MinLon: math.Inf(1), MaxLat: math.Inf(-1), MaxLon: math.Inf(-1)}
+	for _, polygon := range m {
+		box = box.extend(polygon.Outer.BBox())
+	}
+	return box
+}
+
+type IndexItem struct {
+	ID  string
+	Box BBox
+}
+
+// Index is a static R-tree bulk loaded with the Sort-Tile-Recursive
+// algorithm. It is rebuilt whenever the zone set changes, so it does not
+// support incremental inserts.
+type Index struct {
+	root *node
+	size int
+}
+
+type node struct {
+	box      BBox
+	children []*node
+	id       string
+}
+
+func NewIndex(items []IndexItem) *Index {
+	if len(items) == 0 {
+		return &Index{}
+	}
+
+	nodes := make([]*node, len(items))
+	for i, item := range items {
+		nodes[i] = &node{box: item.Box, id: item.ID}
+	}
+
+	for len(nodes) > 1 {
+		nodes = pack(nodes)
+	}
+
+	return &Index{root: nodes[0], size: len(items)}
+}
+
+func (idx *Index) Len() int {
+	return idx.size
+}
+
+// Search returns the IDs of every item whose box contains p. Callers still
+// need an exact containment test on the candidates.
+func (idx *Index) Search(p Point) []string {
+	if idx.root == nil {
+		return nil
+	}
+
+	var ids []string
+	stack := []*node{idx.root}
+	for len(stack) > 0 {
+		n := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		if !n.box.Contains(p) {
+			continue
+		}
+		if n.children == nil {
+			ids = append(ids, n.id)
+			continue
+		}
+		stack = append(stack, n.children...)
+	}
+
+	return ids
+}
+
+// pack groups one tree level into parents of at most nodeCapacity children,
+// tiling first by longitude and then by latitude.
+func pack(nodes []*node) []*node {
+	parentCount := (len(nodes) + nodeCapacity - 1) / nodeCapacity
+	sliceCount := int(math.Ceil(math.Sqrt(float64(parentCount))))
+	sliceSize := sliceCount * nodeCapacity
+
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].box.center().Longitude < nodes[j].box.center().Longitude
+	})
+
+	parents := make([]*node, 0, parentCount)
+	for start := 0; start < len(nodes); start += sliceSize {
+		slice := nodes[start:min(start+sliceSize, len(nodes))]
+		sort.Slice(slice, func(i, j int) bool {
+			return slice[i].box.center().Latitude < slice[j].box.center().Latitude
+		})
+
+		for i := 0; i < len(slice); i += nodeCapacity {
+			children := slice[i:min(i+nodeCapacity, len(slice))]
+			parent := &node{
+				box:      children[0].box,
+				children: append([]*node(nil), children...),
+			}
+			for _, child := range children[1:] {
+				parent.box = parent.box.extend(child.box)
+			}
+			parents = append(parents, parent)
+		}
+	}
+
+	return parents
+}
@@ -0,0 +1,257 @@
+package geofence
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+type benchZone struct {
+	id     string
+	center Point
+	radius float64
+}
+
+// benchZones scatters bus-stop sized circles over greater Jakarta.
+func benchZones(n int) []benchZone {
+	r := rand.New(rand.NewSource(1))
+	zones := make([]benchZone, n)
+	for i := range zones {
+		zones[i] = benchZone{
+			id: fmt.Sprintf("zone-%d", i),
+			center: Point{
+				Latitude:  -6.4 + r.Float64()*0.4,
+				Longitude: 106.6 + r.Float64()*0.4,
+			},
+			radius: 30 + r.Float64()*170,
+		}
+	}
+	return zones
+}
+
+func benchPoints(n int) []Point {
+	r := rand.New(rand.NewSource(2))
+	points := make([]Point, n)
+	for i := range points {
+		points[i] = Point{
+			Latitude:  -6.4 + r.Float64()*0.4,
+			Longitude: 106.6 + r.Float64()*0.4,
+		}
+	}
+	return points
+}
+
+func BenchmarkLinearScan(b *testing.B) {
+	for _, n := range []int{100, 1000, 10000} {
+		zones := benchZones(n)
+		points := benchPoints(1024)
+
+		b.Run(fmt.Sprintf("zones=%d", n), func(b *testing.B) {
+			matches := 0
+			for i := 0; i < b.N; i++ {
+				p := points[i%len(points)]
+				for _, zone := range zones {
+					if IsWithinRadius(zone.center, p, zone.radius) {
+						matches++
+					}
+				}
+			}
+			_ = matches
+		})
+	}
+}
+
+func BenchmarkIndexSearch(b *testing.B) {
+	for _, n := range []int{100, 1000, 10000} {
+		zones := benchZones(n)
+		points := benchPoints(1024)
+
+		byID := make(map[string]benchZone, n)
+		items := make([]IndexItem, n)
+		for i, zone := range zones {
+			byID[zone.id] = zone
+			items[i] = IndexItem{ID: zone.id, Box: CircleBBox(zone.center, zone.radius)}
+		}
+		idx := NewIndex(items)
+
+		b.Run(fmt.Sprintf("zones=%d", n), func(b *testing.B) {
+			matches := 0
+			for i := 0; i < b.N; i++ {
+				p := points[i%len(points)]
+				for _, id := range idx.Search(p) {
+					zone := byID[id]
+					if IsWithinRadius(zone.center, p, zone.radius) {
+						matches++
+					}
+				}
+			}
+			_ = matches
+		})
+	}
+}
+
+func BenchmarkNewIndex(b *testing.B) {
+	zones := benchZones(10000)
+	items := make([]IndexItem, len(zones))
+	for i, zone := range zones {
+		items[i] = IndexItem{ID: zone.id, Box: CircleBBox(zone.center, zone.radius)}
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		NewIndex(items)
+	}
+}
+
+// testZone is a zone of any kind with its exact containment test.
+type testZone struct {
+	id       string
+	box      BBox
+	contains func(Point) bool
+	// probes are points on the zone's own boundary or bounding box.
+	probes []Point
+}
+
+func boxCorners(b BBox) []Point {
+	return []Point{
+		{Latitude: b.MinLat, Longitude: b.MinLon},
+		{Latitude: b.MinLat, Longitude: b.MaxLon},
+		{Latitude: b.MaxLat, Longitude: b.MinLon},
+		{Latitude: b.MaxLat, Longitude: b.MaxLon},
+		{Latitude: b.MinLat, Longitude: (b.MinLon + b.MaxLon) / 2},
+		{Latitude: b.MaxLat, Longitude: (b.MinLon + b.MaxLon) / 2},
+		{Latitude: (b.MinLat + b.MaxLat) / 2, Longitude: b.MinLon},
+		{Latitude: (b.MinLat + b.MaxLat) / 2, Longitude: b.MaxLon},
+	}
+}
+
+// randomZones mixes circles, square polygons with a square hole and buffered
+// two-segment corridors around the given centre.
+func randomZones(r *rand.Rand, n int, lat, lon, spread float64) []testZone {
+	zones := make([]testZone, n)
+	for i := range zones {
+		center := Point{
+			Latitude:  lat + (r.Float64()-0.5)*spread,
+			Longitude: lon + (r.Float64()-0.5)*spread,
+		}
+		id := fmt.Sprintf("zone-%d", i)
+
+		var zone testZone
+		switch i % 3 {
+		case 0:
+			radius := 20 + r.Float64()*2000
+			zone = testZone{
+				box:      CircleBBox(center, radius),
+				contains: func(p Point) bool { return IsWithinRadius(center, p, radius) },
+			}
+			zone.probes = []Point{
+				{Latitude: center.Latitude, Longitude: zone.box.MaxLon},
+				{Latitude: center.Latitude, Longitude: zone.box.MinLon},
+				{Latitude: zone.box.MaxLat, Longitude: center.Longitude},
+				{Latitude: zone.box.MinLat, Longitude: center.Longitude},
+			}
+		case 1:
+			size := 0.001 + r.Float64()*0.02
+			square := func(half float64) Ring {
+				return Ring{
+					{Latitude: center.Latitude - half, Longitude: center.Longitude - half},
+					{Latitude: center.Latitude - half, Longitude: center.Longitude + half},
+					{Latitude: center.Latitude + half, Longitude: center.Longitude + half},
+					{Latitude: center.Latitude + half, Longitude: center.Longitude - half},
+				}
+			}
+			polygon := MultiPolygon{{Outer: square(size), Holes: []Ring{square(size / 3)}}}
+			zone = testZone{
+				box:      polygon.BBox(),
+				contains: polygon.Contains,
+				probes:   append(append([]Point{}, polygon[0].Outer...), polygon[0].Holes[0]...),
+			}
+		default:
+			width := 10 + r.Float64()*500
+			line := Polyline{
+				center,
+				{Latitude: center.Latitude + (r.Float64()-0.5)*0.02, Longitude: center.Longitude + (r.Float64()-0.5)*0.02},
+				{Latitude: center.Latitude + (r.Float64()-0.5)*0.02, Longitude: center.Longitude + (r.Float64()-0.5)*0.02},
+			}
+			zone = testZone{
+				box:      CorridorBBox(line, width),
+				contains: func(p Point) bool { return IsWithinCorridor(line, p, width) },
+				probes:   append([]Point{}, line...),
+			}
+		}
+
+		zone.id = id
+		zone.probes = append(zone.probes, boxCorners(zone.box)...)
+		zones[i] = zone
+	}
+	return zones
+}
+
+// TestIndexSearchMatchesLinearScan checks that the R-tree never drops a zone
+// a linear scan would match, for random points and for points exactly on
+// zone boundaries and bounding-box edges.
+func TestIndexSearchMatchesLinearScan(t *testing.T) {
+	areas := []struct {
+		name     string
+		lat, lon float64
+		spread   float64
+	}{
+		{"jakarta", -6.2, 106.8, 0.5},
+		{"oslo", 59.9, 10.7, 0.5},
+		{"equator", 0, 0, 0.5},
+	}
+
+	for _, area := range areas {
+		t.Run(area.name, func(t *testing.T) {
+			r := rand.New(rand.NewSource(3))
+			// Enough zones for a tree several levels deep.
+			zones := randomZones(r, 600, area.lat, area.lon, area.spread)
+
+			items := make([]IndexItem, len(zones))
+			for i, zone := range zones {
+				items[i] = IndexItem{ID: zone.id, Box: zone.box}
+			}
+			idx := NewIndex(items)
+			if idx.Len() != len(zones) {
+				t.Fatalf("Len() = %d, want %d", idx.Len(), len(zones))
+			}
+
+			var points []Point
+			for i := 0; i < 1000; i++ {
+				points = append(points, Point{
+					Latitude:  area.lat + (r.Float64()-0.5)*area.spread*1.2,
+					Longitude: area.lon + (r.Float64()-0.5)*area.spread*1.2,
+				})
+			}
+			for _, zone := range zones {
+				points = append(points, zone.probes...)
+			}
+
+			for _, p := range points {
+				found := make(map[string]bool)
+				for _, id := range idx.Search(p) {
+					found[id] = true
+				}
+
+				var missing []string
+				for _, zone := range zones {
+					if (zone.contains(p) || zone.box.Contains(p)) && !found[zone.id] {
+						missing = append(missing, zone.id)
+					}
+				}
+				if len(missing) > 0 {
+					sort.Strings(missing)
+					t.Fatalf("Search(%v) missed %v", p, missing)
+				}
+			}
+		})
+	}
+}
+
+func TestIndexEmpty(t *testing.T) {
+	idx := NewIndex(nil)
+	if ids := idx.Search(Point{}); ids != nil {
+		t.Errorf("Search on an empty index = %v, want nil", ids)
+	}
+}
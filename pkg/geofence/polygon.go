@@ -0,0 +1,182 @@
+package geofence
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+)
+
+// Ring is a closed sequence of points. The closing point may be omitted.
+type Ring []Point
+
+// Polygon is an outer ring with optional holes.
+type Polygon struct {
+	Outer Ring
+	Holes []Ring
+}
+
+type MultiPolygon []Polygon
+
+// Contains reports whether target lies inside the ring or on its boundary.
+func (r Ring) Contains(target Point) bool {
+	n := len(r)
+	if n < 3 {
+		return false
+	}
+	if r.onBoundary(target) {
+		return true
+	}
+
+	inside := false
+	for i, j := 0, n-1; i < n; j, i = i, i+1 {
+		a, b := r[i], r[j]
+		if (a.Latitude > target.Latitude) != (b.Latitude > target.Latitude) {
+			x := (b.Longitude-a.Longitude)*(target.Latitude-a.Latitude)/(b.Latitude-a.Latitude) + a.Longitude
+			if target.Longitude < x {
+				inside = !inside
+			}
+		}
+	}
+
+	return inside
+}
+
+// boundaryEpsilon is the tolerance, in degrees, for a point to count as lying
+// on an edge; about a millimetre on the ground.
+const boundaryEpsilon = 1e-8
+
+func (r Ring) onBoundary(target Point) bool {
+	for i, j := 0, len(r)-1; i < len(r); j, i = i, i+1 {
+		if onSegment(r[j], r[i], target) {
+			return true
+		}
+	}
+	return false
+}
+
+func onSegment(a, b, p Point) bool {
+	if p.Longitude < math.Min(a.Longitude, b.Longitude)-boundaryEpsilon ||
+		p.Longitude > math.Max(a.Longitude, b.Longitude)+boundaryEpsilon ||
+		p.Latitude < math.Min(a.Latitude, b.Latitude)-boundaryEpsilon ||
+		p.Latitude > math.Max(a.Latitude, b.Latitude)+boundaryEpsilon {
+		return false
+	}
+
+	dx, dy := b.Longitude-a.Longitude, b.Latitude-a.Latitude
+	length := math.Hypot(dx, dy)
+	if length == 0 {
+		return math.Hypot(p.Longitude-a.Longitude, p.Latitude-a.Latitude) <= boundaryEpsilon
+	}
+	cross := dx*(p.Latitude-a.Latitude) - dy*(p.Longitude-a.Longitude)
+	return math.Abs(cross)/length <= boundaryEpsilon
+}
+
+// Contains reports whether target lies inside the outer ring and outside
+// every hole. The boundaries of the outer ring and of the holes both belong to
+// the polygon.
+func (p Polygon) Contains(target Point) bool {
+	if !p.Outer.Contains(target) {
+		return false
+	}
+
+	for _, hole := range p.Holes {
+		if hole.Contains(target) && !hole.onBoundary(target) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (m MultiPolygon) Contains(target Point) bool {
+	for _, polygon := range m {
+		if polygon.Contains(target) {
+			return true
+		}
+	}
+	return false
+}
+
+func IsWithinPolygon(polygon MultiPolygon, target Point) bool {
+	return polygon.Contains(target)
+}
+
+// Coordinates are encoded the GeoJSON way: [longitude, latitude].
+
+// MarshalJSON writes a closed ring, repeating the first point at the end when
+// it was omitted, as RFC 7946 requires.
+func (r Ring) MarshalJSON() ([]byte, error) {
+	coords := make([][2]float64, len(r), len(r)+1)
+	for i, p := range r {
+		coords[i] = [2]float64{p.Longitude, p.Latitude}
+	}
+	if len(r) > 0 && r[0] != r[len(r)-1] {
+		coords = append(coords, coords[0])
+	}
+	return json.Marshal(coords)
+}
+
+func (r *Ring) UnmarshalJSON(data []byte) error {
+	var coords [][]float64
+	if err := json.Unmarshal(data, &coords); err != nil {
+		return err
+	}
+
+	ring := make(Ring, len(coords))
+	for i, c := range coords {
+		if len(c) < 2 {
+			return fmt.Errorf("invalid coordinate at position %d", i)
+		}
+		ring[i] = Point{Latitude: c[1], Longitude: c[0]}
+	}
+
+	*r = ring
+	return nil
+}
+
+func (p Polygon) MarshalJSON() ([]byte, error) {
+	rings := append([]Ring{p.Outer}, p.Holes...)
+	return json.Marshal(rings)
+}
+
+func (p *Polygon) UnmarshalJSON(data []byte) error {
+	var rings []Ring
+	if err := json.Unmarshal(data, &rings); err != nil {
+		return err
+	}
+	if len(rings) == 0 {
+		return fmt.Errorf("polygon has no rings")
+	}
+
+	p.Outer = rings[0]
+	p.Holes = rings[1:]
+	return nil
+}
+
+// ParseGeoJSONGeometry decodes a GeoJSON Polygon or MultiPolygon geometry.
+func ParseGeoJSONGeometry(data []byte) (MultiPolygon, error) {
+	var geometry struct {
+		Type        string          `json:"type"`
+		Coordinates json.RawMessage `json:"coordinates"`
+	}
+	if err := json.Unmarshal(data, &geometry); err != nil {
+		return nil, err
+	}
+
+	switch geometry.Type {
+	case "Polygon":
+		var polygon Polygon
+		if err := json.Unmarshal(geometry.Coordinates, &polygon); err != nil {
+			return nil, err
+		}
+		return MultiPolygon{polygon}, nil
+	case "MultiPolygon":
+		var multi MultiPolygon
+		if err := json.Unmarshal(geometry.Coordinates, &multi); err != nil {
+			return nil, err
+		}
+		return multi, nil
+	default:
+		return nil, fmt.Errorf("unsupported geometry type: %s", geometry.Type)
+	}
+}
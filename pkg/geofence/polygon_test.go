@@ -0,0 +1,172 @@
+package geofence
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// ring builds a ring from [longitude, latitude] pairs, the GeoJSON order.
+func ring(coords ...[2]float64) Ring {
+	r := make(Ring, len(coords))
+	for i, c := range coords {
+		r[i] = Point{Latitude: c[1], Longitude: c[0]}
+	}
+	return r
+}
+
+func pt(lon, lat float64) Point {
+	return Point{Latitude: lat, Longitude: lon}
+}
+
+func TestMultiPolygonContains(t *testing.T) {
+	square := Polygon{Outer: ring(
+		[2]float64{106.80, -6.20},
+		[2]float64{106.90, -6.20},
+		[2]float64{106.90, -6.10},
+		[2]float64{106.80, -6.10},
+	)}
+	closedSquare := Polygon{Outer: append(append(Ring{}, square.Outer...), square.Outer[0])}
+
+	// An L shape: the notch in the top right is outside.
+	concave := Polygon{Outer: ring(
+		[2]float64{0, 0},
+		[2]float64{4, 0},
+		[2]float64{4, 2},
+		[2]float64{2, 2},
+		[2]float64{2, 4},
+		[2]float64{0, 4},
+	)}
+
+	withHole := Polygon{
+		Outer: ring(
+			[2]float64{0, 0},
+			[2]float64{10, 0},
+			[2]float64{10, 10},
+			[2]float64{0, 10},
+			[2]float64{0, 0},
+		),
+		Holes: []Ring{ring(
+			[2]float64{4, 4},
+			[2]float64{6, 4},
+			[2]float64{6, 6},
+			[2]float64{4, 6},
+			[2]float64{4, 4},
+		)},
+	}
+
+	// A zone around Fiji crossing the antimeridian, split in two per RFC 7946.
+	fiji := MultiPolygon{
+		{Outer: ring(
+			[2]float64{178, -19},
+			[2]float64{180, -19},
+			[2]float64{180, -16},
+			[2]float64{178, -16},
+			[2]float64{178, -19},
+		)},
+		{Outer: ring(
+			[2]float64{-180, -19},
+			[2]float64{-179, -19},
+			[2]float64{-179, -16},
+			[2]float64{-180, -16},
+			[2]float64{-180, -19},
+		)},
+	}
+
+	tests := []struct {
+		name    string
+		polygon MultiPolygon
+		point   Point
+		want    bool
+	}{
+		{"convex inside", MultiPolygon{square}, pt(106.85, -6.15), true},
+		{"convex outside east", MultiPolygon{square}, pt(106.95, -6.15), false},
+		{"convex outside north", MultiPolygon{square}, pt(106.85, -6.05), false},
+		{"convex on left edge", MultiPolygon{square}, pt(106.80, -6.15), true},
+		{"convex on right edge", MultiPolygon{square}, pt(106.90, -6.15), true},
+		{"convex on top edge", MultiPolygon{square}, pt(106.85, -6.10), true},
+		{"convex on bottom edge", MultiPolygon{square}, pt(106.85, -6.20), true},
+		{"convex on vertex", MultiPolygon{square}, pt(106.90, -6.10), true},
+		{"convex in line with edge but outside", MultiPolygon{square}, pt(106.95, -6.10), false},
+
+		{"closed ring inside", MultiPolygon{closedSquare}, pt(106.85, -6.15), true},
+		{"closed ring outside", MultiPolygon{closedSquare}, pt(106.75, -6.15), false},
+		{"closed ring on closing vertex", MultiPolygon{closedSquare}, pt(106.80, -6.20), true},
+
+		{"concave inside lower arm", MultiPolygon{concave}, pt(3, 1), true},
+		{"concave inside upper arm", MultiPolygon{concave}, pt(1, 3), true},
+		{"concave in notch", MultiPolygon{concave}, pt(3, 3), false},
+		{"concave on reflex vertex", MultiPolygon{concave}, pt(2, 2), true},
+		{"concave on notch edge", MultiPolygon{concave}, pt(3, 2), true},
+		{"concave level with reflex vertex", MultiPolygon{concave}, pt(1, 2), true},
+
+		{"hole outside hole", MultiPolygon{withHole}, pt(2, 2), true},
+		{"hole inside hole", MultiPolygon{withHole}, pt(5, 5), false},
+		{"hole on hole edge", MultiPolygon{withHole}, pt(5, 4), true},
+		{"hole on hole vertex", MultiPolygon{withHole}, pt(6, 6), true},
+		{"hole outside outer", MultiPolygon{withHole}, pt(11, 5), false},
+
+		{"antimeridian east of 180", fiji, pt(179.5, -17.5), true},
+		{"antimeridian west of 180", fiji, pt(-179.5, -17.5), true},
+		{"antimeridian on 180", fiji, pt(180, -17.5), true},
+		{"antimeridian on -180", fiji, pt(-180, -17.5), true},
+		{"antimeridian outside west", fiji, pt(-178, -17.5), false},
+		{"antimeridian far side of globe", fiji, pt(0, -17.5), false},
+
+		{"empty", MultiPolygon{}, pt(0, 0), false},
+		{"degenerate ring", MultiPolygon{{Outer: ring([2]float64{0, 0}, [2]float64{1, 1})}}, pt(0.5, 0.5), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.polygon.Contains(tt.point); got != tt.want {
+				t.Errorf("Contains(%v) = %v, want %v", tt.point, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseGeoJSONGeometry(t *testing.T) {
+	data := []byte(`{"type":"Polygon","coordinates":[
+		[[0,0],[10,0],[10,10],[0,10],[0,0]],
+		[[4,4],[6,4],[6,6],[4,6],[4,4]]
+	]}`)
+
+	polygon, err := ParseGeoJSONGeometry(data)
+	if err != nil {
+		t.Fatalf("ParseGeoJSONGeometry: %v", err)
+	}
+	if len(polygon) != 1 || len(polygon[0].Holes) != 1 {
+		t.Fatalf("got %d polygons, want 1 with 1 hole", len(polygon))
+	}
+	if !polygon.Contains(pt(2, 8)) || polygon.Contains(pt(5, 5)) {
+		t.Errorf("parsed polygon does not exclude its hole")
+	}
+
+	if _, err := ParseGeoJSONGeometry([]byte(`{"type":"Point","coordinates":[0,0]}`)); err == nil {
+		t.Errorf("expected an error for a Point geometry")
+	}
+}
+
+func TestRingMarshalJSONClosesRing(t *testing.T) {
+	tests := []struct {
+		name string
+		ring Ring
+		want string
+	}{
+		{"open", ring([2]float64{0, 0}, [2]float64{1, 0}, [2]float64{1, 1}), "[[0,0],[1,0],[1,1],[0,0]]"},
+		{"closed", ring([2]float64{0, 0}, [2]float64{1, 0}, [2]float64{1, 1}, [2]float64{0, 0}), "[[0,0],[1,0],[1,1],[0,0]]"},
+		{"empty", Ring{}, "[]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := json.Marshal(tt.ring)
+			if err != nil {
+				t.Fatalf("Marshal: %v", err)
+			}
+			if string(data) != tt.want {
+				t.Errorf("got %s, want %s", data, tt.want)
+			}
+		})
+	}
+}
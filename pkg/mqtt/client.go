@@ -1,21 +1,70 @@
 package mqtt
 
 import (
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
 	"fmt"
 	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
 
 	mqtt "github.com/eclipse/paho.mqtt.golang"
 	"github.com/fahri/go-tije/internal/config"
 )
 
+// operationTimeout bounds how long Publish and Subscribe wait for the broker.
+// While the client reconnects paho queues QoS 1 messages instead of failing
+// them, which would otherwise block the caller until the broker is back.
+const operationTimeout = 10 * time.Second
+
 type Client struct {
 	client mqtt.Client
+	
+	statusTopic string
+	
+	mu            sync.Mutex
+	subscriptions map[string]mqtt.MessageHandler
+	state         State
+}
+
+// Option customises a Client before it connects.
+type Option func(c *Client, opts *mqtt.ClientOptions)
+
+// WithStatus announces the client's presence on topic: a retained "online"
+// on every connect, a retained "offline" on Disconnect, and "offline" as the
+// Last Will the broker publishes when the connection drops unexpectedly.
+func WithStatus(topic string) Option {
+	return func(c *Client, opts *mqtt.ClientOptions) {
+		c.statusTopic = topic
+		opts.SetWill(topic, "offline", 1, true)
+	}
 }
 
-func NewClient(cfg *config.MQTTConfig) (*Client, error) {
+// State is a snapshot of the broker connection for health checks.
+type State struct {
+	Connected      bool      `json:"connected"`
+	ConnectedSince time.Time `json:"connected_since"`
+	LastError      string    `json:"last_error,omitempty"`
+	Reconnects     int       `json:"reconnects"`
+}
+
+// NewClient connects to the broker and fails if it is unreachable. Once
+// connected, a lost connection is retried with exponential backoff up to
+// cfg.MaxReconnectInterval, and every subscription is restored on reconnect.
+func NewClient(cfg *config.MQTTConfig, options ...Option) (*Client, error) {
+	c := &Client{subscriptions: make(map[string]mqtt.MessageHandler)}
+	
 	opts := mqtt.NewClientOptions()
 	opts.AddBroker(cfg.Broker)
-	opts.SetClientID(cfg.ClientID)
+	opts.SetClientID(ClientID(cfg.ClientID))
+	opts.SetCleanSession(cfg.CleanSession)
+	opts.SetKeepAlive(cfg.KeepAlive)
+	opts.SetAutoReconnect(true)
+	opts.SetMaxReconnectInterval(cfg.MaxReconnectInterval)
 	
 	if cfg.Username != "" {
 		opts.SetUsername(cfg.Username)
@@ -24,38 +73,203 @@ func NewClient(cfg *config.MQTTConfig) (*Client, error) {
 		opts.SetPassword(cfg.Password)
 	}
 	
+	tlsConfig, err := newTLSConfig(&cfg.TLS)
+	if err != nil {
+		return nil, err
+	}
+	if tlsConfig != nil {
+		if !isTLSBroker(cfg.Broker) {
+			log.Printf("MQTT TLS settings are ignored for broker %s; use an ssl:// URL", cfg.Broker)
+		}
+		opts.SetTLSConfig(tlsConfig)
+	}
+	
 	opts.SetDefaultPublishHandler(func(client mqtt.Client, msg mqtt.Message) {
 		log.Printf("Received message: %s from topic: %s\n", msg.Payload(), msg.Topic())
 	})
 	
-	opts.OnConnect = func(client mqtt.Client) {
-		log.Println("Connected to MQTT broker")
-	}
+	opts.OnConnect = c.onConnect
 	
 	opts.OnConnectionLost = func(client mqtt.Client, err error) {
 		log.Printf("Connection lost: %v\n", err)
+		c.mu.Lock()
+		c.state.Connected = false
+		c.state.LastError = err.Error()
+		c.mu.Unlock()
 	}
 	
-	client := mqtt.NewClient(opts)
-	if token := client.Connect(); token.Wait() && token.Error() != nil {
+	opts.OnReconnecting = func(client mqtt.Client, opts *mqtt.ClientOptions) {
+		log.Println("Reconnecting to MQTT broker")
+		c.mu.Lock()
+		c.state.Reconnects++
+		c.mu.Unlock()
+	}
+	
+	for _, option := range options {
+		option(c, opts)
+	}
+	
+	c.client = mqtt.NewClient(opts)
+	if token := c.client.Connect(); token.Wait() && token.Error() != nil {
 		return nil, fmt.Errorf("failed to connect: %v", token.Error())
 	}
 	
-	return &Client{client: client}, nil
+	return c, nil
+}
+
+// onConnect restores the subscriptions after a reconnect. A broker that kept
+// the session (clean session off) still has them, and subscribing again is
+// harmless.
+func (c *Client) onConnect(client mqtt.Client) {
+	log.Println("Connected to MQTT broker")
+	
+	c.mu.Lock()
+	c.state.Connected = true
+	c.state.ConnectedSince = time.Now()
+	c.state.LastError = ""
+	subscriptions := make(map[string]mqtt.MessageHandler, len(c.subscriptions))
+	for topic, handler := range c.subscriptions {
+		subscriptions[topic] = handler
+	}
+	c.mu.Unlock()
+	
+	if c.statusTopic != "" {
+		token := client.Publish(c.statusTopic, 1, true, "online")
+		if token.Wait() && token.Error() != nil {
+			log.Printf("Failed to publish status to %s: %v", c.statusTopic, token.Error())
+		}
+	}
+	
+	for topic, handler := range subscriptions {
+		token := client.Subscribe(topic, 1, handler)
+		if token.Wait() && token.Error() != nil {
+			log.Printf("Failed to resubscribe to %s: %v", topic, token.Error())
+			continue
+		}
+		log.Printf("Resubscribed to %s", topic)
+	}
 }
 
+// Subscribe subscribes to topic and keeps it subscribed across reconnects.
 func (c *Client) Subscribe(topic string, handler mqtt.MessageHandler) error {
 	token := c.client.Subscribe(topic, 1, handler)
-	token.Wait()
-	return token.Error()
+	if !token.WaitTimeout(operationTimeout) {
+		return fmt.Errorf("subscribe to %s timed out after %v", topic, operationTimeout)
+	}
+	if err := token.Error(); err != nil {
+		return err
+	}
+	
+	c.mu.Lock()
+	c.subscriptions[topic] = handler
+	c.mu.Unlock()
+	return nil
+}
+
+// State reports the current connection state.
+func (c *Client) State() State {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	
+	state := c.state
+	state.Connected = c.client.IsConnectionOpen()
+	return state
 }
 
 func (c *Client) Publish(topic string, payload []byte) error {
 	token := c.client.Publish(topic, 1, false, payload)
-	token.Wait()
+	if !token.WaitTimeout(operationTimeout) {
+		return fmt.Errorf("publish to %s timed out after %v", topic, operationTimeout)
+	}
 	return token.Error()
 }
 
 func (c *Client) Disconnect() {
+	if c.statusTopic != "" && c.client.IsConnectionOpen() {
+		token := c.client.Publish(c.statusTopic, 1, true, "offline")
+		token.WaitTimeout(time.Second)
+	}
 	c.client.Disconnect(250)
+}
+
+// newTLSConfig builds the TLS settings of the broker connection, or returns
+// nil when none are configured so the defaults apply.
+func newTLSConfig(cfg *config.MQTTTLSConfig) (*tls.Config, error) {
+	if cfg.CAFile == "" && cfg.CertFile == "" && cfg.KeyFile == "" && !cfg.InsecureSkipVerify && len(cfg.ALPN) == 0 {
+		return nil, nil
+	}
+	
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: cfg.InsecureSkipVerify,
+		NextProtos:         cfg.ALPN,
+	}
+	
+	if cfg.CAFile != "" {
+		pem, err := os.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA file: %v", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA file %s", cfg.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	
+	if cfg.CertFile != "" || cfg.KeyFile != "" {
+		if cfg.CertFile == "" || cfg.KeyFile == "" {
+			return nil, fmt.Errorf("both a client certificate and key file are required")
+		}
+		cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %v", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	
+	return tlsConfig, nil
+}
+
+func isTLSBroker(broker string) bool {
+	switch strings.ToLower(strings.SplitN(broker, "://", 2)[0]) {
+	case "ssl", "tls", "mqtts", "mqtt+ssl", "tcps", "wss":
+		return true
+	}
+	return false
+}
+
+// ClientID expands {hostname} and {random} in a configured client ID. Brokers
+// disconnect a client when another connects with the same ID, so replicas
+// sharing one configuration need distinct IDs; {hostname} keeps the ID stable
+// across restarts of the same container.
+func ClientID(pattern string) string {
+	id := pattern
+	if strings.Contains(id, "{hostname}") {
+		hostname, err := os.Hostname()
+		if err != nil || hostname == "" {
+			hostname = randomSuffix()
+		}
+		id = strings.ReplaceAll(id, "{hostname}", hostname)
+	}
+	for strings.Contains(id, "{random}") {
+		id = strings.Replace(id, "{random}", randomSuffix(), 1)
+	}
+	return id
+}
+
+// SharedTopic returns the shared subscription "$share/<group>/<topic>", or
+// topic itself when group is empty. The broker hands each message of a shared
+// subscription to one member of the group.
+func SharedTopic(group, topic string) string {
+	if group == "" {
+		return topic
+	}
+	return "$share/" + group + "/" + topic
+}
+
+func randomSuffix() string {
+	b := make([]byte, 4)
+	rand.Read(b)
+	return hex.EncodeToString(b)
 }
\ No newline at end of file
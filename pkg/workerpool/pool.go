@@ -0,0 +1,101 @@
+package workerpool
+
+import (
+	"context"
+	"errors"
+	"hash/fnv"
+	"sync"
+	"time"
+)
+
+// ErrClosed is returned by Submit after Close.
+var ErrClosed = errors.New("worker pool closed")
+
+// Task is a unit of work. Its context carries the pool's per-task timeout.
+type Task func(ctx context.Context)
+
+// Pool runs tasks on a fixed set of workers, each with its own bounded queue.
+// Tasks submitted with the same key always land on the same worker, so they
+// run one at a time in submission order while different keys run in parallel.
+type Pool struct {
+	shards  []chan Task
+	timeout time.Duration
+	wg      sync.WaitGroup
+
+	mu     sync.RWMutex
+	closed bool
+}
+
+// New starts workers goroutines with queueSize pending tasks each. A task
+// still running after timeout has its context cancelled; zero disables the
+// timeout.
+func New(workers, queueSize int, timeout time.Duration) *Pool {
+	p := &Pool{
+		shards:  make([]chan Task, max(workers, 1)),
+		timeout: timeout,
+	}
+
+	for i := range p.shards {
+		p.shards[i] = make(chan Task, max(queueSize, 0))
+		p.wg.Add(1)
+		go p.work(p.shards[i])
+	}
+
+	return p
+}
+
+// Submit queues task on the worker owning key. It blocks while that worker's
+// queue is full, until ctx is done.
+func (p *Pool) Submit(ctx context.Context, key string, task Task) error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.closed {
+		return ErrClosed
+	}
+
+	select {
+	case p.shards[p.shard(key)] <- task:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close stops accepting tasks and waits for the queued ones to finish.
+func (p *Pool) Close() {
+	p.mu.Lock()
+	if !p.closed {
+		p.closed = true
+		for _, shard := range p.shards {
+			close(shard)
+		}
+	}
+	p.mu.Unlock()
+
+	p.wg.Wait()
+}
+
+func (p *Pool) shard(key string) int {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return int(h.Sum32() % uint32(len(p.shards)))
+}
+
+func (p *Pool) work(queue <-chan Task) {
+	defer p.wg.Done()
+
+	for task := range queue {
+		p.run(task)
+	}
+}
+
+func (p *Pool) run(task Task) {
+	ctx := context.Background()
+	if p.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.timeout)
+		defer cancel()
+	}
+
+	task(ctx)
+}